@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dolthub/robot-blogger/pkg"
+)
+
+type command struct {
+	usage       string
+	description string
+	// bootstrap creates the store's database before the blogger opens it.
+	bootstrap bool
+	run       func(ctx context.Context, blogger pkg.Blogger, args []string) error
+}
+
+// commands are keyed by their space separated name, ie "style create".
+var commands = map[string]command{
+	"init": {
+		usage:       "init",
+		description: "creates the store's database, tables and vector index if missing, checks vector support and detects the vector dimensions",
+		bootstrap:   true,
+		run:         initStore,
+	},
+	"docs list": {
+		usage:       "docs list [-name name] [-doc-type type] [-model model] [-json]",
+		description: "lists the stored documents with their doc type, md5, chunk count, model and ingestion time",
+		run:         docsList,
+	},
+	"docs stats": {
+		usage:       "docs stats [-json]",
+		description: "prints the number of documents and chunks, chunks per doc type, average chunk length and models",
+		run:         docsStats,
+	},
+	"docs delete": {
+		usage:       "docs delete [-name name] [-doc-type type] [-model model] [-dry-run]",
+		description: "deletes the chunks of the stored documents matching every given filter",
+		run:         docsDelete,
+	},
+	"export": {
+		usage:       "export [-o file] [-name name] [-doc-type type] [-model model]",
+		description: "writes the stored chunks with their metadata and vectors as json lines, to stdout by default",
+		run:         exportChunks,
+	},
+	"import": {
+		usage:       "import <file>",
+		description: "adds chunks written by export to the store without embedding them again",
+		run:         importChunks,
+	},
+	"store snapshot": {
+		usage:       "store snapshot -o <archive>",
+		description: "writes the stored chunks, vectors and metadata, with the splitter settings and model, to a tar.zst archive",
+		run:         storeSnapshot,
+	},
+	"store restore": {
+		usage:       "store restore <archive>",
+		description: "recreates a store written by store snapshot in an empty store, without embedding it again",
+		run:         storeRestore,
+	},
+	"history list": {
+		usage:       "history list [-limit n]",
+		description: "lists the most recent generations saved with -save-history",
+		run:         historyList,
+	},
+	"history show": {
+		usage:       "history show <id>",
+		description: "prints a saved generation, with the documents and context it was written from",
+		run:         historyShow,
+	},
+	"reembed": {
+		usage:       "reembed -target-model <model> [-target-runner runner] [-target-store-name name] [-target-branch branch] [-target-vector-dimensions n] [-name name] [-doc-type type] [-model model]",
+		description: "copies stored chunks into another store or dolt branch, embedded with another model",
+		run:         reembed,
+	},
+	"revise": {
+		usage:       "revise <draft-file>",
+		description: "revises a draft interactively, using -prompt-file, -topic, -output-format and -length if set",
+		run:         reviseDraft,
+	},
+	"branches list": {
+		usage:       "branches list",
+		description: "lists the branches of a dolt store",
+		run:         branchesList,
+	},
+	"branches diff": {
+		usage:       "branches diff <from> <to>",
+		description: "compares the chunks stored per doc type and model on two dolt branches",
+		run:         branchesDiff,
+	},
+	"branches merge": {
+		usage:       "branches merge <branch>",
+		description: "merges a dolt branch into the store's branch, -branch or the default branch",
+		run:         branchesMerge,
+	},
+	"search diff": {
+		usage:       "search diff [-k n] <from> <to> <query>",
+		description: "compares the top k chunks a search returns on two dolt revisions",
+		run:         searchDiff,
+	},
+	"versions list": {
+		usage:       "versions list [-limit n]",
+		description: "lists the commits of a dolt store, newest first",
+		run:         versionsList,
+	},
+	"versions checkout": {
+		usage:       "versions checkout [-branch name] <revision>",
+		description: "creates a dolt branch at a prior commit, tag or branch of the store",
+		run:         versionsCheckout,
+	},
+	"style create": {
+		usage:       "style create [-samples n] -doc-type <type> <name>",
+		description: "distills a style guide from stored documents of a doc type",
+		run:         styleCreate,
+	},
+	"style list": {
+		usage:       "style list",
+		description: "lists the stored style profiles",
+		run:         styleList,
+	},
+	"style show": {
+		usage:       "style show <name>",
+		description: "prints a stored style profile",
+		run:         styleShow,
+	},
+}
+
+// findCommand returns the command named by the longest prefix of args, and
+// the remaining args.
+func findCommand(args []string) (command, []string, error) {
+	for i := len(args); i > 0; i-- {
+		if cmd, ok := commands[strings.Join(args[:i], " ")]; ok {
+			return cmd, args[i:], nil
+		}
+	}
+	return command{}, nil, fmt.Errorf("unknown command: %s", strings.Join(args, " "))
+}
+
+func runCommand(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	cmd, rest, err := findCommand(args)
+	if err != nil {
+		return err
+	}
+	return cmd.run(ctx, blogger, rest)
+}
+
+func printCommands() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println()
+	fmt.Println("commands:")
+	for _, name := range names {
+		fmt.Printf("  %s\n    \t%s\n", commands[name].usage, commands[name].description)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dolthub/robot-blogger/pkg"
+)
+
+// docsFilterFlags adds the flags selecting stored documents to fs.
+func docsFilterFlags(fs *flag.FlagSet) func() pkg.DocumentFilter {
+	name := fs.String("name", "", "only documents with this file name")
+	docType := fs.String("doc-type", "", "only documents of this doc type")
+	model := fs.String("model", "", "only documents embedded by this model")
+	return func() pkg.DocumentFilter {
+		return pkg.DocumentFilter{
+			Name:          *name,
+			DocSourceType: pkg.DocSourceType(*docType),
+			Model:         pkg.Model(*model),
+		}
+	}
+}
+
+func docsList(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("docs list", flag.ContinueOnError)
+	filter := docsFilterFlags(fs)
+	asJSON := fs.Bool("json", false, "prints the documents as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	docs, err := blogger.Documents(ctx, filter())
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(docs)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDOC TYPE\tMD5\tCHUNKS\tMODEL\tINGESTED")
+	for _, doc := range docs {
+		ingestedAt := "-"
+		if !doc.IngestedAt.IsZero() {
+			ingestedAt = doc.IngestedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s/%s\t%s\n", doc.Name, doc.DocSourceType, doc.MD5, doc.Chunks, doc.Runner, doc.Model, ingestedAt)
+	}
+	return w.Flush()
+}
+
+func docsStats(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("docs stats", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "prints the stats as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	stats, err := blogger.DocumentStats(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(stats)
+	}
+
+	fmt.Printf("DOCUMENTS: %d\n", stats.Documents)
+	fmt.Printf("CHUNKS: %d\n", stats.Chunks)
+	fmt.Printf("AVERAGE CHUNK LENGTH: %.0f characters\n", stats.AverageChunkChars)
+	fmt.Printf("MODELS: %s\n", strings.Join(stats.Models, ", "))
+	fmt.Println()
+
+	docTypes := make([]string, 0, len(stats.ChunksPerDocType))
+	for docType := range stats.ChunksPerDocType {
+		docTypes = append(docTypes, docType)
+	}
+	sort.Strings(docTypes)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DOC TYPE\tCHUNKS")
+	for _, docType := range docTypes {
+		fmt.Fprintf(w, "%s\t%d\n", docType, stats.ChunksPerDocType[docType])
+	}
+	return w.Flush()
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func docsDelete(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("docs delete", flag.ContinueOnError)
+	filter := docsFilterFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "only counts the chunks that would be deleted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	n, err := blogger.DeleteDocuments(ctx, filter(), *dryRun)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Printf("would delete %d chunks matching %s\n", n, filter())
+		return nil
+	}
+	fmt.Printf("deleted %d chunks matching %s\n", n, filter())
+	return nil
+}
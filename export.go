@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dolthub/robot-blogger/pkg"
+)
+
+func exportChunks(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	filter := docsFilterFlags(fs)
+	output := fs.String("o", "", "the file to write, stdout if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	n, err := blogger.Export(ctx, w, filter())
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Printf("exported %d chunks to %s\n", n, *output)
+	}
+	return nil
+}
+
+func importChunks(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: import <file>")
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := blogger.Import(ctx, f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d chunks from %s\n", n, args[0])
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dolthub/robot-blogger/pkg"
+)
+
+func historyList(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("history list", flag.ContinueOnError)
+	limit := fs.Int("limit", 20, "the number of generations to list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	gens, err := blogger.SavedGenerations(ctx, *limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tFORMAT\tMODEL\tLENGTH\tTOPIC")
+	for _, gen := range gens {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\n", gen.ID, gen.CreatedAt.Format("2006-01-02 15:04:05"), gen.Format, gen.Model, gen.Length, gen.Topic)
+	}
+	return w.Flush()
+}
+
+func historyShow(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("history show requires a generation id")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid generation id: %s", args[0])
+	}
+	gen, err := blogger.SavedGeneration(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%d\n", gen.ID)
+	fmt.Fprintf(w, "CREATED:\t%s\n", gen.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "MODEL:\t%s/%s\n", gen.Runner, gen.Model)
+	fmt.Fprintf(w, "TOPIC:\t%s\n", gen.Topic)
+	fmt.Fprintf(w, "FORMAT:\t%s\n", gen.Format)
+	fmt.Fprintf(w, "LENGTH:\t%d words, requested %d\n", gen.Length, gen.RequestedLength)
+	fmt.Fprintf(w, "PROMPT SET:\t%s\n", gen.PromptSet)
+	if gen.StoreVersion != "" {
+		fmt.Fprintf(w, "STORE VERSION:\t%s\n", gen.StoreVersion)
+	}
+	if gen.StyleProfile != "" {
+		fmt.Fprintf(w, "STYLE:\t%s\n", gen.StyleProfile)
+	}
+	fmt.Fprintf(w, "TOKENS:\t%d prompt, %d completion, %d total\n", gen.Usage.PromptTokens, gen.Usage.CompletionTokens, gen.Usage.TotalTokens)
+	fmt.Fprintf(w, "DURATION:\t%s, %s retrieving\n", gen.Duration, gen.RetrievalDuration)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("USER PROMPT:")
+	fmt.Println(strings.TrimSpace(gen.UserPrompt))
+
+	fmt.Println()
+	fmt.Println("RETRIEVED DOCUMENTS:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDOC TYPE\tMD5\tSCORE")
+	for _, doc := range gen.Documents {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.4f\n", doc.Name, doc.DocSourceType, doc.MD5, doc.Score)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if gen.Context != "" {
+		fmt.Println()
+		fmt.Println("CONTEXT:")
+		fmt.Println(strings.TrimSpace(gen.Context))
+	}
+
+	fmt.Println()
+	fmt.Println("CONTENT:")
+	fmt.Println(strings.TrimSpace(gen.Content))
+	return nil
+}
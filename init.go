@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dolthub/robot-blogger/pkg"
+)
+
+func initStore(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: init")
+	}
+	report, err := blogger.Init(ctx)
+	if err != nil {
+		return err
+	}
+
+	database := "exists"
+	if report.CreatedDatabase {
+		database = "created"
+	}
+	dimensions := fmt.Sprint(report.VectorDimensions)
+	if report.ProbedDimensions {
+		dimensions += " (detected)"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "STORE\t%s\n", report.StoreType)
+	fmt.Fprintf(w, "DATABASE\t%s (%s)\n", report.Database, database)
+	fmt.Fprintf(w, "SERVER VERSION\t%s\n", report.ServerVersion)
+	if report.VectorVersion != "" {
+		fmt.Fprintf(w, "PGVECTOR VERSION\t%s\n", report.VectorVersion)
+	}
+	fmt.Fprintf(w, "VECTOR DIMENSIONS\t%s\n", dimensions)
+	return w.Flush()
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/dolthub/robot-blogger/pkg"
@@ -28,11 +29,56 @@ var storeName = flag.String("store-name", "", "the name of the vector store to u
 var host = flag.String("host", "", "the vector store host to connect to")
 var port = flag.Int("port", 0, "the vector store port to connect to")
 var user = flag.String("user", "", "the vector store user to connect to")
+var dsn = flag.String("dsn", "", "the DSN or URL of the vector store server, used instead of -host, -port, -user and VECTOR_STORE_PASSWORD")
+var tlsMode = flag.String("tls", "", "the TLS mode of vector store connections, disable, require or verify-full")
+var tlsCAFile = flag.String("tls-ca", "", "the CA certificate file used to verify the vector store server")
+var connectTimeout = flag.Duration("connect-timeout", 0, "the timeout for connecting to the vector store, ie 10s")
+var readTimeout = flag.Duration("read-timeout", 0, "the timeout for vector store queries, ie 1m")
+var maxOpenConns = flag.Int("max-open-conns", 0, "the maximum number of open vector store connections, unlimited if 0")
+var maxIdleConns = flag.Int("max-idle-conns", 0, "the maximum number of idle vector store connections, the driver default if 0")
+var connMaxLifetime = flag.Duration("conn-max-lifetime", 0, "the maximum time a vector store connection is reused, unlimited if 0")
+var maxRetries = flag.Int("max-retries", pkg.DefaultMaxRetries, "the number of times a failed llm or embedding call is retried with exponential backoff")
+var requestsPerMinute = flag.Int("requests-per-minute", 0, "the maximum number of llm and embedding calls per minute, unlimited if 0")
+var tokensPerMinute = flag.Int("tokens-per-minute", 0, "the maximum number of estimated llm and embedding tokens per minute, unlimited if 0")
+var embedBatchSize = flag.Int("embed-batch-size", pkg.DefaultEmbedBatchSize, "the maximum number of chunks Store embeds in one call, unlimited if 0")
+var embedBatchTokens = flag.Int("embed-batch-tokens", pkg.DefaultEmbedBatchTokens, "the maximum number of estimated tokens Store embeds in one call, unlimited if 0")
+var embeddingCache = flag.String("embedding-cache", "", "the file embeddings are cached in, defaults to robot-blogger/embeddings.db in the user cache directory")
+var noEmbeddingCache = flag.Bool("no-embedding-cache", false, "embeds every chunk without caching the embeddings")
 var topic = flag.String("topic", "", "the topic of the content to generate")
-var length = flag.Int("length", 500, "the length of the content to generate")
-var vectorDimensions = flag.Int("vector-dimensions", 1536, "the number of dimensions to use for the vector store")
-var outputFormat = flag.String("output-format", "markdown", "the output format of the content to generate")
+var length = flag.Int("length", 0, "the length of the content to generate, defaults to the output format's length")
+var vectorDimensions = flag.Int("vector-dimensions", 0, "the number of dimensions of the model's vectors, detected by embedding a probe string if 0")
+var outputFormat = flag.String("output-format", pkg.DefaultOutputFormat, fmt.Sprintf("the output format of the content to generate, one of: %s", strings.Join(pkg.OutputFormatNames(), ", ")))
 var includeFileExt = flag.String("include-file-ext", ".md", "the file extension used to filter files which should be included in the store")
+var longForm = flag.Bool("long-form", false, "generates an outline first, then writes each section separately")
+var enforceLength = flag.Bool("enforce-length", false, "continues or condenses the generated content until it is within -length-tolerance of -length")
+var lengthTolerance = flag.Float64("length-tolerance", pkg.DefaultLengthTolerance, "the allowed fractional difference between the requested and generated length")
+var promptSet = flag.String("prompt-set", pkg.DefaultPromptSet, "the name of the prompt set to use")
+var promptDir = flag.String("prompt-dir", "", "the directory to load prompt sets from, defaults to the built-in prompt sets")
+var mergeMessages = flag.Bool("merge-messages", false, "sends each prompt as a single human message, for models that do not support system messages")
+var styleProfile = flag.String("style", "", "the name of the style profile to write generated content in")
+var render = flag.String("render", "", "renders the generated content as html, text or eml")
+var outputFile = flag.String("output-file", "", "the file to write rendered content to, defaults to stdout")
+var emailFrom = flag.String("email-from", "", "the From address used when rendering eml")
+var emailTo = flag.String("email-to", "", "a comma separated list of To addresses used when rendering eml")
+var factCheck = flag.Bool("fact-check", false, "checks the generated content's claims against the vector store")
+var saveHistory = flag.Bool("save-history", false, "saves each generation to the history table of the vector store database")
+var branch = flag.String("branch", "", "the dolt branch to store to and generate from, created from the default branch if missing")
+var asOf = flag.String("as-of", "", "the dolt commit, tag or branch to retrieve context from, defaults to the current state of the store")
+var tag = flag.String("tag", "", "tags the dolt commit created by a Store or by saving a generation")
+var reviseFlag = flag.Bool("revise", false, "after generating, revises the content interactively with feedback read from stdin")
+var factCheckRevise = flag.Bool("fact-check-revise", false, "revises the generated content to remove unsupported claims, implies -fact-check")
+
+// splitterSettings are the settings of the splitter Store uses, recorded in
+// snapshots.
+var splitterSettings = pkg.SplitterSettings{
+	Type:         "markdown",
+	ChunkSize:    512,
+	ChunkOverlap: 128,
+}
+
+// config is the configuration of the blogger commands run with, which
+// commands like reembed use to configure a second blogger.
+var config *pkg.Config
 
 func main() {
 	flag.Parse()
@@ -42,11 +88,13 @@ func main() {
 		return
 	}
 
-	if *host == "" || *port == 0 {
-		printErrorUsageAndExit(errors.New("host and port are required"))
-	}
-	if *user == "" {
-		printErrorUsageAndExit(errors.New("user is required"))
+	if *dsn == "" {
+		if *host == "" || *port == 0 {
+			printErrorUsageAndExit(errors.New("host and port are required"))
+		}
+		if *user == "" {
+			printErrorUsageAndExit(errors.New("user is required"))
+		}
 	}
 	if *model == "" {
 		printErrorUsageAndExit(errors.New("model is required"))
@@ -75,9 +123,6 @@ func main() {
 	} else if *dolt {
 		sn = pkg.Dolt
 	} else if *mariadb {
-		if *vectorDimensions == 0 {
-			printErrorUsageAndExit(errors.New("vector dimensions are required for mariadb"))
-		}
 		sn = pkg.MariaDB
 	} else {
 		printErrorUsageAndExit(errors.New("unsupported store"))
@@ -86,12 +131,16 @@ func main() {
 	if *storeName == "" {
 		printErrorUsageAndExit(errors.New("store name is required"))
 	}
+	if *branch != "" && sn != pkg.Dolt {
+		printErrorUsageAndExit(errors.New("branch is only supported for dolt"))
+	}
 
+	isCommand := flag.NArg() > 0
 	storeOnly := false
 	var sourceType pkg.DocSourceType
 	var splitter textsplitter.TextSplitter
 
-	if *docType != "" {
+	if *docType != "" && !isCommand {
 		if *includeFileExt == "" {
 			printErrorUsageAndExit(errors.New("include-file-ext is required"))
 		}
@@ -102,8 +151,8 @@ func main() {
 		// todo: make this configurable
 		splitter = textsplitter.NewMarkdownTextSplitter(
 			textsplitter.WithModelName(*model),
-			textsplitter.WithChunkSize(512),    // default is 512
-			textsplitter.WithChunkOverlap(128), // default is 100
+			textsplitter.WithChunkSize(splitterSettings.ChunkSize),       // default is 512
+			textsplitter.WithChunkOverlap(splitterSettings.ChunkOverlap), // default is 100
 			textsplitter.WithCodeBlocks(true),
 			textsplitter.WithHeadingHierarchy(true),
 			textsplitter.WithCodeBlocks(true),
@@ -117,12 +166,27 @@ func main() {
 		return filepath.Ext(path) == *includeFileExt
 	}
 
-	if !storeOnly {
+	switch *render {
+	case "", "html", "text", "eml":
+	default:
+		printErrorUsageAndExit(fmt.Errorf("unsupported render format: %s", *render))
+	}
+
+	var cmd command
+	if isCommand {
+		var err error
+		if cmd, _, err = findCommand(flag.Args()); err != nil {
+			printErrorUsageAndExit(err)
+		}
+	} else if !storeOnly {
 		if *topic == "" {
 			printErrorUsageAndExit(errors.New("topic is required"))
 		}
-		if *length == 0 {
-			printErrorUsageAndExit(errors.New("length is required"))
+		if *length < 0 {
+			printErrorUsageAndExit(errors.New("length must not be negative"))
+		}
+		if _, err := pkg.GetOutputFormat(*outputFormat); err != nil {
+			printErrorUsageAndExit(err)
 		}
 	} else {
 		if _, err := os.Stat(docsInputsDir); os.IsNotExist(err) {
@@ -146,7 +210,12 @@ func main() {
 		logger.Info("blogger total time", zap.Duration("duration", time.Since(start)))
 	}()
 
-	config := pkg.NewConfig()
+	prompts, err := pkg.LoadPromptSet(*promptDir, *promptSet)
+	if err != nil {
+		printErrorAndExit(err)
+	}
+
+	config = pkg.NewConfig()
 	config.WithRunner(runner)
 	config.WithModel(pkg.Model(*model))
 	config.WithStoreType(sn)
@@ -154,13 +223,45 @@ func main() {
 	config.WithUser(*user)
 	config.WithPassword(storePassword)
 	config.WithPort(*port)
+	config.WithDSN(*dsn)
+	config.WithTLSMode(*tlsMode)
+	config.WithTLSCAFile(*tlsCAFile)
+	config.WithConnectTimeout(*connectTimeout)
+	config.WithReadTimeout(*readTimeout)
+	config.WithMaxOpenConns(*maxOpenConns)
+	config.WithMaxIdleConns(*maxIdleConns)
+	config.WithConnMaxLifetime(*connMaxLifetime)
+	config.WithMaxRetries(*maxRetries)
+	config.WithRateLimit(*requestsPerMinute, *tokensPerMinute)
+	config.WithEmbedBatch(*embedBatchSize, *embedBatchTokens)
+	if !*noEmbeddingCache {
+		cache, err := openEmbeddingCache(*embeddingCache)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		if cache != nil {
+			defer cache.Close()
+			config.WithEmbeddingCache(cache)
+		}
+	}
 	config.WithVectorDimensions(*vectorDimensions)
 	config.WithStoreName(*storeName)
 	config.WithSplitter(splitter)
+	config.WithSplitterSettings(splitterSettings)
 	config.WithIncludeFileFunc(includeFileFunc)
-	config.WithPreContentSystemPrompt(SystemPromptPreContentBlock)
-	config.WithPostContentSystemPromptTemplate(SystemPromptPostContentBlockTemplate)
-	config.WithRefineContextSystemPrompt(RefineContextSystemPromptPrefix)
+	config.WithPromptSet(prompts)
+	config.WithStyleProfile(*styleProfile)
+	config.WithMergeMessages(*mergeMessages)
+	config.WithLongForm(*longForm)
+	config.WithEnforceLength(*enforceLength)
+	config.WithLengthTolerance(*lengthTolerance)
+	config.WithFactCheck(*factCheck || *factCheckRevise)
+	config.WithFactCheckRevise(*factCheckRevise)
+	config.WithSaveHistory(*saveHistory)
+	config.WithBranch(*branch)
+	config.WithAsOf(*asOf)
+	config.WithTag(*tag)
+	config.WithBootstrap(cmd.bootstrap)
 
 	blogger, err := pkg.NewBlogger(
 		ctx,
@@ -172,30 +273,127 @@ func main() {
 	}
 	defer blogger.Close()
 
-	if storeOnly {
+	if isCommand {
+		err = runCommand(ctx, blogger, flag.Args())
+	} else if storeOnly {
 		err = blogger.Store(ctx, sourceType, docsInputsDir)
 	} else {
-		if *promptFile == "" {
-			printErrorUsageAndExit(errors.New("prompt file is required"))
+		err = generate(ctx, blogger)
+	}
+	if err != nil {
+		printErrorAndExit(err)
+	}
+}
+
+func generate(ctx context.Context, blogger pkg.Blogger) error {
+	if *promptFile == "" {
+		printErrorUsageAndExit(errors.New("prompt file is required"))
+	}
+	data, err := os.ReadFile(*promptFile)
+	if err != nil {
+		return err
+	}
+
+	gen, err := blogger.Generate(ctx, string(data), *topic, *length, *outputFormat)
+	if err != nil {
+		return err
+	}
+
+	if gen.Rendered != strings.TrimSpace(gen.Content) {
+		fmt.Println()
+		fmt.Println()
+		fmt.Printf("RENDERED CONTENT (%s):\n", gen.Format)
+		fmt.Println(gen.Rendered)
+	}
+
+	fmt.Println()
+	fmt.Println()
+	fmt.Printf("LENGTH: %d words, requested %d\n", gen.Length, gen.RequestedLength)
+	fmt.Printf("PROMPT SET: %s\n", gen.PromptSet)
+	fmt.Printf("TOKENS: %d prompt, %d completion, %d total\n", gen.Usage.PromptTokens, gen.Usage.CompletionTokens, gen.Usage.TotalTokens)
+	if gen.StoreVersion != "" {
+		fmt.Printf("STORE VERSION: %s\n", gen.StoreVersion)
+	}
+	if gen.ID != 0 {
+		fmt.Printf("HISTORY ID: %d\n", gen.ID)
+	}
+
+	if len(gen.FormatProblems) > 0 {
+		fmt.Println()
+		fmt.Printf("FORMAT PROBLEMS (%s):\n", gen.Format)
+		for _, problem := range gen.FormatProblems {
+			fmt.Printf("- %s\n", problem)
 		}
-		f, err := os.Open(*promptFile)
+	}
+
+	if gen.FactCheck != nil {
+		fmt.Println()
+		fmt.Println("FACT CHECK REPORT:")
+		fmt.Println(gen.FactCheck.String())
+		if gen.FactCheck.RevisedContent != "" {
+			fmt.Println()
+			fmt.Println("REVISED CONTENT:")
+			fmt.Println(gen.FactCheck.RevisedContent)
+		}
+	}
+
+	if *reviseFlag {
+		gen, err = revise(ctx, blogger, gen)
 		if err != nil {
-			printErrorAndExit(err)
+			return err
 		}
-		data, err := io.ReadAll(f)
+	}
+
+	if *render != "" {
+		return writeRendered(gen)
+	}
+	return nil
+}
+
+func writeRendered(gen *pkg.Generation) error {
+	var out io.Writer = os.Stdout
+	if *outputFile != "" {
+		f, err := os.Create(*outputFile)
 		if err != nil {
-			printErrorAndExit(err)
+			return err
 		}
-		err = blogger.Generate(ctx, string(data), *topic, *length, *outputFormat)
+		defer f.Close()
+		out = f
+	} else {
+		fmt.Println()
+		fmt.Printf("RENDERED %s:\n", strings.ToUpper(*render))
 	}
-	if err != nil {
-		printErrorAndExit(err)
+
+	switch *render {
+	case "html":
+		html, err := pkg.RenderHTML(gen.Rendered)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(out, html)
+		return err
+	case "text":
+		_, err := io.WriteString(out, pkg.RenderText(gen.Rendered))
+		return err
+	case "eml":
+		email, err := pkg.NewEmail(gen.Rendered, *topic)
+		if err != nil {
+			return err
+		}
+		email.From = *emailFrom
+		if *emailTo != "" {
+			email.To = strings.Split(*emailTo, ",")
+		}
+		_, err = email.WriteTo(out)
+		return err
 	}
+	return nil
 }
 
 func Usage() {
-	fmt.Println("robot-blogger [options]")
+	fmt.Println("robot-blogger [options] [command]")
 	flag.PrintDefaults()
+	printCommands()
 }
 
 func printErrorAndExit(err error) {
@@ -208,3 +406,21 @@ func printErrorUsageAndExit(err error) {
 	Usage()
 	os.Exit(1)
 }
+
+// openEmbeddingCache opens the embedding cache at path, or at the default path
+// if it is empty. If another run has the cache open, it warns and returns nil,
+// so the run embeds without it.
+func openEmbeddingCache(path string) (*pkg.EmbeddingCache, error) {
+	if path == "" {
+		var err error
+		if path, err = pkg.DefaultEmbeddingCachePath(); err != nil {
+			return nil, err
+		}
+	}
+	cache, err := pkg.OpenEmbeddingCache(path)
+	if errors.Is(err, pkg.ErrEmbeddingCacheInUse) {
+		fmt.Fprintf(os.Stderr, "warning: %s, embedding without it\n", err)
+		return nil, nil
+	}
+	return cache, err
+}
@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultEmbedBatchSize   = 64
+	DefaultEmbedBatchTokens = 32768
+)
+
+// storeBatch is the chunks of the files Store embeds and inserts together.
+// Files are never split across batches, so a file is either stored whole or
+// not at all.
+type storeBatch struct {
+	files  []string
+	docs   []schema.Document
+	tokens int
+	// hashes are the name and md5 of each file in the batch, since Has does
+	// not see them until the batch is flushed.
+	hashes map[string]bool
+}
+
+// batchFull reports whether adding docs, of tokens estimated tokens, would take
+// the batch over the embedding batch limits.
+func (b *bloggerImpl) batchFull(batch *storeBatch, docs []schema.Document, tokens int) bool {
+	if len(batch.docs) == 0 {
+		return false
+	}
+	if b.batchSize > 0 && len(batch.docs)+len(docs) > b.batchSize {
+		return true
+	}
+	return b.batchTokens > 0 && batch.tokens+tokens > b.batchTokens
+}
+
+// has reports whether the batch holds a file named file with content md5.
+func (batch *storeBatch) has(file string, md5 string) bool {
+	return batch.hashes[file+"\x00"+md5]
+}
+
+func (batch *storeBatch) add(file string, md5 string, docs []schema.Document, tokens int) {
+	if batch.hashes == nil {
+		batch.hashes = make(map[string]bool)
+	}
+	batch.hashes[file+"\x00"+md5] = true
+	batch.files = append(batch.files, file)
+	batch.docs = append(batch.docs, docs...)
+	batch.tokens += tokens
+}
+
+// flushBatch embeds the batch's chunks and inserts them into the store, then
+// empties the batch and returns the files it stored.
+func (b *bloggerImpl) flushBatch(ctx context.Context, batch *storeBatch) ([]string, error) {
+	if len(batch.docs) == 0 {
+		return nil, nil
+	}
+
+	start := time.Now()
+	texts := make([]string, len(batch.docs))
+	for i, doc := range batch.docs {
+		texts[i] = doc.PageContent
+	}
+	vectors, err := b.embedChunks(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = b.s.AddDocuments(ctx, batch.docs, vectorstores.WithEmbedder(precomputedEmbedder(vectors))); err != nil {
+		return nil, err
+	}
+	b.logger.Info("finished storing documents", zap.Strings("names", batch.files), zap.Int("chunks", len(batch.docs)), zap.Duration("duration", time.Since(start)))
+
+	files := batch.files
+	*batch = storeBatch{}
+	return files, nil
+}
+
+// embedChunks embeds texts in calls of at most batchSize texts and
+// batchTokens estimated tokens.
+func (b *bloggerImpl) embedChunks(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); {
+		end, tokens := start, 0
+		for end < len(texts) {
+			t := estimateTokens(texts[end])
+			if end > start && ((b.batchSize > 0 && end-start >= b.batchSize) || (b.batchTokens > 0 && tokens+t > b.batchTokens)) {
+				break
+			}
+			tokens += t
+			end++
+		}
+
+		batch, err := b.embedder.EmbedDocuments(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("embedded %d of %d chunks", len(batch), end-start)
+		}
+		vectors = append(vectors, batch...)
+		start = end
+	}
+	return vectors, nil
+}
@@ -0,0 +1,118 @@
+package pkg
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// batchingEmbedder records the size of each batch it is asked to embed.
+type batchingEmbedder struct {
+	batches []int
+}
+
+func (e *batchingEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	e.batches = append(e.batches, len(texts))
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+func (e *batchingEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	return []float32{float32(len(text))}, nil
+}
+
+func TestStoreBatchHas(t *testing.T) {
+	batch := &storeBatch{}
+	if batch.has("a.md", "1") {
+		t.Error("expected an empty batch to have no files")
+	}
+
+	batch.add("a.md", "1", []schema.Document{{PageContent: "a"}, {PageContent: "b"}}, 2)
+	batch.add("b.md", "2", []schema.Document{{PageContent: "c"}}, 1)
+
+	tests := []struct {
+		file     string
+		md5      string
+		expected bool
+	}{
+		{"a.md", "1", true},
+		{"b.md", "2", true},
+		{"a.md", "2", false},
+		{"c.md", "1", false},
+	}
+	for _, test := range tests {
+		if has := batch.has(test.file, test.md5); has != test.expected {
+			t.Errorf("%s %s: expected %t, got %t", test.file, test.md5, test.expected, has)
+		}
+	}
+	if !reflect.DeepEqual(batch.files, []string{"a.md", "b.md"}) || len(batch.docs) != 3 || batch.tokens != 3 {
+		t.Errorf("unexpected batch: %+v", batch)
+	}
+}
+
+func TestBatchFull(t *testing.T) {
+	docs := func(n int) []schema.Document { return make([]schema.Document, n) }
+	tests := []struct {
+		batchSize   int
+		batchTokens int
+		batch       *storeBatch
+		docs        int
+		tokens      int
+		expected    bool
+	}{
+		{2, 100, &storeBatch{}, 5, 500, false},
+		{2, 100, &storeBatch{docs: docs(1), tokens: 10}, 1, 10, false},
+		{2, 100, &storeBatch{docs: docs(1), tokens: 10}, 2, 10, true},
+		{2, 100, &storeBatch{docs: docs(1), tokens: 10}, 1, 91, true},
+		{0, 0, &storeBatch{docs: docs(100), tokens: 1000}, 100, 1000, false},
+	}
+	for i, test := range tests {
+		b := &bloggerImpl{batchSize: test.batchSize, batchTokens: test.batchTokens}
+		if full := b.batchFull(test.batch, docs(test.docs), test.tokens); full != test.expected {
+			t.Errorf("test %d: expected %t, got %t", i, test.expected, full)
+		}
+	}
+}
+
+func TestEmbedChunks(t *testing.T) {
+	// each text of 40 characters is estimated at 11 tokens.
+	text := strings.Repeat("a", 40)
+	tests := []struct {
+		batchSize   int
+		batchTokens int
+		texts       int
+		expected    []int
+	}{
+		{0, 0, 5, []int{5}},
+		{2, 0, 5, []int{2, 2, 1}},
+		{0, 22, 5, []int{2, 2, 1}},
+		{3, 100, 7, []int{3, 3, 1}},
+		// a text over the token limit is still embedded, on its own.
+		{10, 5, 2, []int{1, 1}},
+	}
+	for _, test := range tests {
+		embedder := &batchingEmbedder{}
+		b := &bloggerImpl{embedder: embedder, batchSize: test.batchSize, batchTokens: test.batchTokens}
+
+		texts := make([]string, test.texts)
+		for i := range texts {
+			texts[i] = text
+		}
+		vectors, err := b.embedChunks(context.Background(), texts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vectors) != len(texts) {
+			t.Errorf("expected %d vectors, got %d", len(texts), len(vectors))
+		}
+		if !reflect.DeepEqual(embedder.batches, test.expected) {
+			t.Errorf("size %d, tokens %d: expected batches %v, got %v", test.batchSize, test.batchTokens, test.expected, embedder.batches)
+		}
+	}
+}
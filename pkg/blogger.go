@@ -1,6 +1,10 @@
 package pkg
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 type Runner string
 type Model string
@@ -18,7 +22,55 @@ const (
 )
 
 type Blogger interface {
+	Init(ctx context.Context) (*BootstrapReport, error)
 	Store(ctx context.Context, docSourceType DocSourceType, dir string) error
-	Generate(ctx context.Context, userPrompt string, topic string, length int, outputFormat string) error
+	Generate(ctx context.Context, userPrompt string, topic string, length int, outputFormat string) (*Generation, error)
+	Documents(ctx context.Context, filter DocumentFilter) ([]StoredDocument, error)
+	DocumentStats(ctx context.Context) (*DocumentStats, error)
+	DeleteDocuments(ctx context.Context, filter DocumentFilter, dryRun bool) (int, error)
+	Reembed(ctx context.Context, target Blogger, filter DocumentFilter, progress func(ReembedProgress)) (*ReembedProgress, error)
+	Export(ctx context.Context, w io.Writer, filter DocumentFilter) (int, error)
+	Import(ctx context.Context, r io.Reader) (int, error)
+	Snapshot(ctx context.Context, w io.Writer) (*SnapshotManifest, error)
+	Restore(ctx context.Context, r io.Reader) (*SnapshotManifest, error)
+	FactCheck(ctx context.Context, content string) (*FactCheckReport, error)
+	Revise(ctx context.Context, gen *Generation) (*Revision, error)
+	CreateStyleProfile(ctx context.Context, name string, docSourceType DocSourceType, numSamples int) (*StyleProfile, error)
+	StyleProfile(ctx context.Context, name string) (*StyleProfile, error)
+	StyleProfiles(ctx context.Context) ([]StyleProfile, error)
+	SavedGeneration(ctx context.Context, id int64) (*Generation, error)
+	SavedGenerations(ctx context.Context, limit int) ([]Generation, error)
+	Versions(ctx context.Context, limit int) ([]StoreVersion, error)
+	CheckoutVersion(ctx context.Context, revision string, branch string) (string, error)
+	Branches(ctx context.Context) ([]StoreBranch, error)
+	DiffBranches(ctx context.Context, from string, to string) (*BranchDiff, error)
+	MergeBranch(ctx context.Context, branch string) (string, error)
+	DiffSearch(ctx context.Context, query string, k int, from string, to string) (*SearchDiff, error)
 	Close() error
 }
+
+// Generation is the result of a single Generate run. ID is set once the
+// generation is saved to the history table.
+type Generation struct {
+	ID                int64
+	Content           string
+	UserPrompt        string
+	Topic             string
+	Context           string
+	Format            string
+	Rendered          string
+	FormatProblems    []string
+	RequestedLength   int
+	Length            int
+	PromptSet         string
+	StyleProfile      string
+	FactCheck         *FactCheckReport
+	Runner            Runner
+	Model             Model
+	Documents         []RetrievedDocument
+	StoreVersion      string
+	Usage             TokenUsage
+	RetrievalDuration time.Duration
+	Duration          time.Duration
+	CreatedAt         time.Time
+}
@@ -0,0 +1,199 @@
+package pkg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tmc/langchaingo/embeddings"
+)
+
+// minMariaDBVersion is the first MariaDB release with the VECTOR type.
+var minMariaDBVersion = []int{11, 7}
+
+// dimensionsProbe is the text embedded to find the number of dimensions of
+// the embedding model.
+const dimensionsProbe = "robot-blogger"
+
+// BootstrapReport describes what NewBlogger found and created while
+// bootstrapping a store with Config.Bootstrap set.
+type BootstrapReport struct {
+	StoreType       StoreType
+	Database        string
+	CreatedDatabase bool
+	ServerVersion   string
+	// VectorVersion is the version of the pgvector extension, for Postgres.
+	VectorVersion    string
+	VectorDimensions int
+	// ProbedDimensions is true if VectorDimensions was found by embedding a
+	// probe string, rather than configured.
+	ProbedDimensions bool
+}
+
+// Init creates the tables robot-blogger keeps beside the embeddings, and
+// returns what NewBlogger did to bootstrap the store.
+func (b *bloggerImpl) Init(ctx context.Context) (*BootstrapReport, error) {
+	if b.bootstrap == nil {
+		return nil, errors.New("store was not bootstrapped, set Config.Bootstrap")
+	}
+	if err := b.s.CreateTables(ctx); err != nil {
+		return nil, err
+	}
+	if err := b.checkDimensions(ctx); err != nil {
+		return nil, err
+	}
+	b.bootstrap.VectorDimensions = b.dimensions.dims
+	b.bootstrap.ProbedDimensions = b.dimensions.probed
+	return b.bootstrap, b.commit(ctx, "Initialize robot-blogger tables")
+}
+
+// bootstrapStore creates the store's database if it is missing, and checks
+// that the server supports vectors.
+func bootstrapStore(ctx context.Context, config *Config) (*BootstrapReport, error) {
+	report := &BootstrapReport{StoreType: config.StoreType, Database: config.StoreName}
+	var err error
+	switch config.StoreType {
+	case Dolt:
+		err = bootstrapDolt(ctx, config, report)
+	case MariaDB:
+		err = bootstrapMariaDB(ctx, config, report)
+	case Postgres:
+		err = bootstrapPostgres(ctx, config, report)
+	default:
+		err = fmt.Errorf("unsupported vector store: %s", config.StoreType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func bootstrapDolt(ctx context.Context, config *Config, report *BootstrapReport) error {
+	connectionString, err := config.connectionString("")
+	if err != nil {
+		return err
+	}
+	db, err := config.openMySQL(connectionString)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = db.QueryRowContext(ctx, "SELECT dolt_version()").Scan(&report.ServerVersion); err != nil {
+		return fmt.Errorf("the vector store server is not a Dolt server: %w", err)
+	}
+	report.CreatedDatabase, err = mysqlCreateDatabase(ctx, db, config.StoreName)
+	return err
+}
+
+func bootstrapMariaDB(ctx context.Context, config *Config, report *BootstrapReport) error {
+	connectionString, err := config.connectionString("")
+	if err != nil {
+		return err
+	}
+	db, err := config.openMySQL(connectionString)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&report.ServerVersion); err != nil {
+		return err
+	}
+	if !strings.Contains(report.ServerVersion, "MariaDB") {
+		return fmt.Errorf("the vector store server is not a MariaDB server, found version %s", report.ServerVersion)
+	}
+	if compareVersions(report.ServerVersion, minMariaDBVersion) < 0 {
+		return fmt.Errorf("MariaDB %s does not support vectors, %d.%d or later is required", report.ServerVersion, minMariaDBVersion[0], minMariaDBVersion[1])
+	}
+	report.CreatedDatabase, err = mysqlCreateDatabase(ctx, db, config.StoreName)
+	return err
+}
+
+func bootstrapPostgres(ctx context.Context, config *Config, report *BootstrapReport) error {
+	connectionString, err := config.connectionString("postgres")
+	if err != nil {
+		return err
+	}
+	conn, err := pgx.Connect(ctx, connectionString)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	if err = conn.QueryRow(ctx, "SHOW server_version").Scan(&report.ServerVersion); err != nil {
+		return err
+	}
+	err = conn.QueryRow(ctx, "SELECT default_version FROM pg_available_extensions WHERE name = 'vector'").Scan(&report.VectorVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("the pgvector extension is not installed on the vector store server")
+	}
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	if err = conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", config.StoreName).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if _, err = conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{config.StoreName}.Sanitize()); err != nil {
+		return err
+	}
+	report.CreatedDatabase = true
+	return nil
+}
+
+// mysqlCreateDatabase creates the database name if it does not exist, and
+// reports whether it did.
+func mysqlCreateDatabase(ctx context.Context, db *sql.DB, name string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.schemata WHERE schema_name = ?", name).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	_, err = db.ExecContext(ctx, "CREATE DATABASE IF NOT EXISTS `"+strings.ReplaceAll(name, "`", "``")+"`")
+	return err == nil, err
+}
+
+// probeVectorDimensions returns the number of dimensions of the vectors e
+// embeds text into.
+func probeVectorDimensions(ctx context.Context, e embeddings.Embedder) (int, error) {
+	v, err := e.EmbedQuery(ctx, dimensionsProbe)
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed a probe string: %w", err)
+	}
+	if len(v) == 0 {
+		return 0, errors.New("failed to embed a probe string: empty vector")
+	}
+	return len(v), nil
+}
+
+// compareVersions compares the leading numeric components of version, ie
+// 11.7.2-MariaDB, to minimum, returning -1, 0 or 1.
+func compareVersions(version string, minimum []int) int {
+	version, _, _ = strings.Cut(version, "-")
+	parts := strings.Split(version, ".")
+	for i, m := range minimum {
+		n := 0
+		if i < len(parts) {
+			n, _ = strconv.Atoi(parts[i])
+		}
+		if n != m {
+			if n < m {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
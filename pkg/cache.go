@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tmc/langchaingo/embeddings"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+var ErrEmbeddingCacheInUse = errors.New("embedding cache is in use by another process")
+
+// EmbeddingCache is a local file of the vectors of embedded texts, keyed by
+// the sha256 of the text in a bucket per runner and model, so a text is
+// embedded once however many stores it is stored in.
+type EmbeddingCache struct {
+	db *bolt.DB
+}
+
+// DefaultEmbeddingCachePath returns robot-blogger/embeddings.db in the user's
+// cache directory.
+func DefaultEmbeddingCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "robot-blogger", "embeddings.db"), nil
+}
+
+// OpenEmbeddingCache opens the embedding cache at path, creating it if it
+// does not exist. It returns ErrEmbeddingCacheInUse if another process has
+// it open.
+func OpenEmbeddingCache(path string) (*EmbeddingCache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%w: %s", ErrEmbeddingCacheInUse, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &EmbeddingCache{db: db}, nil
+}
+
+func (c *EmbeddingCache) Close() error {
+	return c.db.Close()
+}
+
+// client wraps client so that it only embeds the texts that are not in the
+// cache for runner and model.
+func (c *EmbeddingCache) client(client embeddings.EmbedderClient, runner Runner, model Model, logger *zap.Logger) embeddings.EmbedderClient {
+	return &cachingEmbedder{
+		cache:  c,
+		client: client,
+		bucket: []byte(fmt.Sprintf("%s/%s", runner, model)),
+		logger: logger,
+	}
+}
+
+type cachingEmbedder struct {
+	cache  *EmbeddingCache
+	client embeddings.EmbedderClient
+	bucket []byte
+	logger *zap.Logger
+}
+
+var _ embeddings.EmbedderClient = &cachingEmbedder{}
+
+func (e *cachingEmbedder) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([][]byte, len(texts))
+	for i, text := range texts {
+		sum := sha256.Sum256([]byte(text))
+		keys[i] = sum[:]
+	}
+
+	err := e.cache.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(e.bucket)
+		if bucket == nil {
+			return nil
+		}
+		for i, key := range keys {
+			if value := bucket.Get(key); value != nil {
+				vectors[i] = decodeVector(value)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// texts repeated in the call are embedded once.
+	var misses []string
+	missed := make(map[string][]int)
+	hits := len(texts)
+	for i, text := range texts {
+		if vectors[i] != nil {
+			continue
+		}
+		if _, ok := missed[text]; !ok {
+			misses = append(misses, text)
+		}
+		missed[text] = append(missed[text], i)
+		hits--
+	}
+	e.logger.Debug("embedding cache", zap.Int("hits", hits), zap.Int("misses", len(texts)-hits))
+	if len(misses) == 0 {
+		return vectors, nil
+	}
+
+	embedded, err := e.client.CreateEmbedding(ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(misses) {
+		return nil, fmt.Errorf("embedded %d of %d texts", len(embedded), len(misses))
+	}
+
+	err = e.cache.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(e.bucket)
+		if err != nil {
+			return err
+		}
+		for j, text := range misses {
+			indexes := missed[text]
+			for _, i := range indexes {
+				vectors[i] = embedded[j]
+			}
+			if err = bucket.Put(keys[indexes[0]], encodeVector(embedded[j])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+// decodeVector copies the vector out of b, which bolt only keeps valid for
+// the transaction it was read in.
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
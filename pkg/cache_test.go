@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// countingEmbedder embeds each text as a vector of its length, and records the
+// texts it was asked to embed.
+type countingEmbedder struct {
+	texts []string
+}
+
+func (e *countingEmbedder) CreateEmbedding(_ context.Context, texts []string) ([][]float32, error) {
+	e.texts = append(e.texts, texts...)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), 0.5}
+	}
+	return vectors, nil
+}
+
+func openTestEmbeddingCache(t *testing.T) (*EmbeddingCache, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cache", "embeddings.db")
+	cache, err := OpenEmbeddingCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cache.Close() })
+	return cache, path
+}
+
+func TestCachingEmbedder(t *testing.T) {
+	cache, _ := openTestEmbeddingCache(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+	client := &countingEmbedder{}
+	e := cache.client(client, OllamaRunner, "model", zap.New(core))
+
+	tests := []struct {
+		texts    []string
+		embedded []string
+		hits     int64
+		misses   int64
+	}{
+		{[]string{"a", "bb", "a"}, []string{"a", "bb"}, 0, 3},
+		{[]string{"bb", "ccc", "a"}, []string{"ccc"}, 2, 1},
+		{[]string{"ccc", "ccc"}, nil, 2, 0},
+	}
+	for _, test := range tests {
+		client.texts = nil
+		logs.TakeAll()
+
+		vectors, err := e.CreateEmbedding(context.Background(), test.texts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(client.texts, test.embedded) {
+			t.Errorf("%q: expected to embed %q, embedded %q", test.texts, test.embedded, client.texts)
+		}
+		for i, text := range test.texts {
+			if expected := []float32{float32(len(text)), 0.5}; !reflect.DeepEqual(vectors[i], expected) {
+				t.Errorf("%q: expected %v for %q, got %v", test.texts, expected, text, vectors[i])
+			}
+		}
+
+		entries := logs.FilterMessage("embedding cache").All()
+		if len(entries) != 1 {
+			t.Fatalf("%q: expected 1 log entry, got %d", test.texts, len(entries))
+		}
+		fields := entries[0].ContextMap()
+		if fields["hits"] != test.hits || fields["misses"] != test.misses {
+			t.Errorf("%q: expected %d hits and %d misses, got %v and %v", test.texts, test.hits, test.misses, fields["hits"], fields["misses"])
+		}
+	}
+}
+
+func TestCachingEmbedderBucketsByModel(t *testing.T) {
+	cache, _ := openTestEmbeddingCache(t)
+	first := &countingEmbedder{}
+	second := &countingEmbedder{}
+
+	if _, err := cache.client(first, OllamaRunner, "first", zap.NewNop()).CreateEmbedding(context.Background(), []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.client(second, OllamaRunner, "second", zap.NewNop()).CreateEmbedding(context.Background(), []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(second.texts) != 1 {
+		t.Errorf("expected another model to miss the cache, embedded %q", second.texts)
+	}
+}
+
+func TestOpenEmbeddingCacheInUse(t *testing.T) {
+	_, path := openTestEmbeddingCache(t)
+	if _, err := OpenEmbeddingCache(path); !errors.Is(err, ErrEmbeddingCacheInUse) {
+		t.Errorf("expected ErrEmbeddingCacheInUse, got %v", err)
+	}
+}
+
+func TestEncodeVector(t *testing.T) {
+	for _, v := range [][]float32{{}, {1}, {-0.25, 3.5, 1e-7}} {
+		if decoded := decodeVector(encodeVector(v)); !reflect.DeepEqual(decoded, v) {
+			t.Errorf("expected %v, got %v", v, decoded)
+		}
+	}
+}
@@ -1,26 +1,88 @@
 package pkg
 
-import "github.com/tmc/langchaingo/textsplitter"
+import (
+	"time"
+
+	"github.com/tmc/langchaingo/textsplitter"
+)
 
 type Config struct {
-	Runner                          Runner
-	Model                           Model
-	StoreType                       StoreType
-	Host                            string
-	User                            string
-	Password                        string
-	Port                            int
-	VectorDimensions                int
-	StoreName                       string
-	Splitter                        textsplitter.TextSplitter
-	IncludeFileFunc                 func(path string) bool
-	PreContentSystemPrompt          string
-	PostContentSystemPromptTemplate string
-	RefineContextSystemPrompt       string
+	Runner           Runner
+	Model            Model
+	StoreType        StoreType
+	Host             string
+	User             string
+	Password         string
+	Port             int
+	VectorDimensions int
+	StoreName        string
+	Splitter         textsplitter.TextSplitter
+	SplitterSettings SplitterSettings
+	IncludeFileFunc  func(path string) bool
+	PromptSet        *PromptSet
+	StyleProfile     string
+	MergeMessages    bool
+	FactCheck        bool
+	FactCheckRevise  bool
+	LongForm         bool
+	EnforceLength    bool
+	LengthTolerance  float64
+	SaveHistory      bool
+	AsOf             string
+	Branch           string
+	Tag              string
+	Bootstrap        bool
+	// AllowVectorDimensionsChange lets a store with vectors of other
+	// dimensions be opened, ie to reembed it with a new model.
+	AllowVectorDimensionsChange bool
+	// DSN, if set, is the DSN or URL of the vector store server, used instead
+	// of Host, Port, User and Password. Its database is replaced by StoreName.
+	DSN string
+	// TLSMode is one of TLSDisable, TLSRequire or TLSVerifyFull. Postgres
+	// also accepts its other sslmode values.
+	TLSMode         string
+	TLSCAFile       string
+	ConnectTimeout  time.Duration
+	ReadTimeout     time.Duration
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// MaxRetries is the number of times a failed llm or embedding call is
+	// retried, waiting RetryBackoff, doubled each retry up to MaxRetryBackoff,
+	// with jitter.
+	MaxRetries      int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// RequestsPerMinute and TokensPerMinute rate limit llm and embedding
+	// calls to the runner. Zero means unlimited.
+	RequestsPerMinute int
+	TokensPerMinute   int
+	// After CircuitBreakerThreshold consecutive failed calls, calls fail
+	// without reaching the runner for CircuitBreakerCooldown. Zero disables
+	// the circuit breaker.
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+	// Store embeds the chunks of several files together, in calls of at most
+	// EmbedBatchSize chunks and EmbedBatchTokens estimated tokens. Zero means
+	// unlimited.
+	EmbedBatchSize   int
+	EmbedBatchTokens int
+	// EmbeddingCache, if set, is used to embed each text only once per runner
+	// and model. NewBlogger does not close it.
+	EmbeddingCache *EmbeddingCache
 }
 
 func NewConfig() *Config {
-	return &Config{}
+	return &Config{
+		LengthTolerance:         DefaultLengthTolerance,
+		MaxRetries:              DefaultMaxRetries,
+		RetryBackoff:            DefaultRetryBackoff,
+		MaxRetryBackoff:         DefaultMaxRetryBackoff,
+		CircuitBreakerThreshold: DefaultCircuitBreakerThreshold,
+		CircuitBreakerCooldown:  DefaultCircuitBreakerCooldown,
+		EmbedBatchSize:          DefaultEmbedBatchSize,
+		EmbedBatchTokens:        DefaultEmbedBatchTokens,
+	}
 }
 
 func (c *Config) WithRunner(runner Runner) *Config {
@@ -73,22 +135,156 @@ func (c *Config) WithSplitter(splitter textsplitter.TextSplitter) *Config {
 	return c
 }
 
+func (c *Config) WithSplitterSettings(settings SplitterSettings) *Config {
+	c.SplitterSettings = settings
+	return c
+}
+
 func (c *Config) WithIncludeFileFunc(includeFileFunc func(path string) bool) *Config {
 	c.IncludeFileFunc = includeFileFunc
 	return c
 }
 
-func (c *Config) WithPreContentSystemPrompt(preContentSystemPrompt string) *Config {
-	c.PreContentSystemPrompt = preContentSystemPrompt
+func (c *Config) WithPromptSet(promptSet *PromptSet) *Config {
+	c.PromptSet = promptSet
+	return c
+}
+
+func (c *Config) WithStyleProfile(styleProfile string) *Config {
+	c.StyleProfile = styleProfile
+	return c
+}
+
+func (c *Config) WithMergeMessages(mergeMessages bool) *Config {
+	c.MergeMessages = mergeMessages
+	return c
+}
+
+func (c *Config) WithFactCheck(factCheck bool) *Config {
+	c.FactCheck = factCheck
+	return c
+}
+
+func (c *Config) WithFactCheckRevise(factCheckRevise bool) *Config {
+	c.FactCheckRevise = factCheckRevise
+	return c
+}
+
+func (c *Config) WithLongForm(longForm bool) *Config {
+	c.LongForm = longForm
+	return c
+}
+
+func (c *Config) WithEnforceLength(enforceLength bool) *Config {
+	c.EnforceLength = enforceLength
+	return c
+}
+
+func (c *Config) WithLengthTolerance(lengthTolerance float64) *Config {
+	c.LengthTolerance = lengthTolerance
+	return c
+}
+
+func (c *Config) WithSaveHistory(saveHistory bool) *Config {
+	c.SaveHistory = saveHistory
+	return c
+}
+
+func (c *Config) WithAsOf(asOf string) *Config {
+	c.AsOf = asOf
+	return c
+}
+
+func (c *Config) WithBranch(branch string) *Config {
+	c.Branch = branch
+	return c
+}
+
+func (c *Config) WithTag(tag string) *Config {
+	c.Tag = tag
+	return c
+}
+
+func (c *Config) WithBootstrap(bootstrap bool) *Config {
+	c.Bootstrap = bootstrap
+	return c
+}
+
+func (c *Config) WithAllowVectorDimensionsChange(allow bool) *Config {
+	c.AllowVectorDimensionsChange = allow
+	return c
+}
+
+func (c *Config) WithDSN(dsn string) *Config {
+	c.DSN = dsn
+	return c
+}
+
+func (c *Config) WithTLSMode(mode string) *Config {
+	c.TLSMode = mode
+	return c
+}
+
+func (c *Config) WithTLSCAFile(path string) *Config {
+	c.TLSCAFile = path
+	return c
+}
+
+func (c *Config) WithConnectTimeout(timeout time.Duration) *Config {
+	c.ConnectTimeout = timeout
+	return c
+}
+
+func (c *Config) WithReadTimeout(timeout time.Duration) *Config {
+	c.ReadTimeout = timeout
+	return c
+}
+
+func (c *Config) WithMaxOpenConns(n int) *Config {
+	c.MaxOpenConns = n
+	return c
+}
+
+func (c *Config) WithMaxIdleConns(n int) *Config {
+	c.MaxIdleConns = n
+	return c
+}
+
+func (c *Config) WithConnMaxLifetime(lifetime time.Duration) *Config {
+	c.ConnMaxLifetime = lifetime
+	return c
+}
+
+func (c *Config) WithMaxRetries(n int) *Config {
+	c.MaxRetries = n
+	return c
+}
+
+func (c *Config) WithRetryBackoff(backoff time.Duration, maxBackoff time.Duration) *Config {
+	c.RetryBackoff = backoff
+	c.MaxRetryBackoff = maxBackoff
+	return c
+}
+
+func (c *Config) WithRateLimit(requestsPerMinute int, tokensPerMinute int) *Config {
+	c.RequestsPerMinute = requestsPerMinute
+	c.TokensPerMinute = tokensPerMinute
+	return c
+}
+
+func (c *Config) WithCircuitBreaker(threshold int, cooldown time.Duration) *Config {
+	c.CircuitBreakerThreshold = threshold
+	c.CircuitBreakerCooldown = cooldown
 	return c
 }
 
-func (c *Config) WithPostContentSystemPromptTemplate(postContentSystemPromptTemplate string) *Config {
-	c.PostContentSystemPromptTemplate = postContentSystemPromptTemplate
+func (c *Config) WithEmbedBatch(size int, tokens int) *Config {
+	c.EmbedBatchSize = size
+	c.EmbedBatchTokens = tokens
 	return c
 }
 
-func (c *Config) WithRefineContextSystemPrompt(refineContextSystemPrompt string) *Config {
-	c.RefineContextSystemPrompt = refineContextSystemPrompt
+func (c *Config) WithEmbeddingCache(cache *EmbeddingCache) *Config {
+	c.EmbeddingCache = cache
 	return c
 }
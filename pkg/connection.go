@@ -0,0 +1,175 @@
+package pkg
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TLS modes, named after Postgres' sslmode values.
+const (
+	TLSDisable    = "disable"
+	TLSRequire    = "require"
+	TLSVerifyFull = "verify-full"
+)
+
+// mysqlTLSConfigName is the name the TLS config of verify-full MySQL
+// protocol connections is registered under.
+const mysqlTLSConfigName = "robot-blogger"
+
+// connectionString returns the DSN of databaseName on the configured server,
+// with the configured TLS settings and timeouts. If Config.DSN is set, it is
+// used instead of the host, port, user and password, with its database
+// replaced by databaseName.
+func (c *Config) connectionString(databaseName string) (string, error) {
+	switch c.StoreType {
+	case Postgres:
+		return c.postgresConnectionString(databaseName)
+	case Dolt, MariaDB:
+		return c.mysqlConnectionString(databaseName)
+	default:
+		return "", fmt.Errorf("unsupported vector store: %s", c.StoreType)
+	}
+}
+
+func (c *Config) postgresConnectionString(databaseName string) (string, error) {
+	u := &url.URL{Scheme: "postgres"}
+	if c.DSN != "" {
+		var err error
+		if u, err = url.Parse(c.DSN); err != nil {
+			return "", fmt.Errorf("invalid postgres dsn: %w", err)
+		}
+	} else {
+		u.Host = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+		if c.Password == "" {
+			u.User = url.User(c.User)
+		} else {
+			u.User = url.UserPassword(c.User, c.Password)
+		}
+	}
+	u.Path = "/" + databaseName
+
+	q := u.Query()
+	if c.TLSMode != "" {
+		q.Set("sslmode", c.TLSMode)
+	}
+	if c.TLSCAFile != "" {
+		q.Set("sslrootcert", c.TLSCAFile)
+	}
+	if c.ConnectTimeout > 0 {
+		q.Set("connect_timeout", strconv.Itoa(max(1, int(c.ConnectTimeout.Seconds()))))
+	}
+	if c.ReadTimeout > 0 {
+		q.Set("statement_timeout", strconv.FormatInt(c.ReadTimeout.Milliseconds(), 10))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (c *Config) mysqlConnectionString(databaseName string) (string, error) {
+	cfg := mysql.NewConfig()
+	if c.DSN != "" {
+		var err error
+		if cfg, err = mysql.ParseDSN(c.DSN); err != nil {
+			return "", fmt.Errorf("invalid %s dsn: %w", c.StoreType, err)
+		}
+	} else {
+		cfg.User = c.User
+		cfg.Passwd = c.Password
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	}
+	cfg.DBName = databaseName
+	cfg.ParseTime = true
+	if c.StoreType == Dolt {
+		cfg.MultiStatements = true
+	}
+	if c.ConnectTimeout > 0 {
+		cfg.Timeout = c.ConnectTimeout
+	}
+	if c.ReadTimeout > 0 {
+		cfg.ReadTimeout = c.ReadTimeout
+	}
+
+	switch c.TLSMode {
+	case "":
+	case TLSDisable:
+		cfg.TLSConfig = "false"
+	case TLSRequire:
+		cfg.TLSConfig = "skip-verify"
+	case TLSVerifyFull:
+		host, _, err := net.SplitHostPort(cfg.Addr)
+		if err != nil {
+			return "", err
+		}
+		tlsConfig := &tls.Config{ServerName: host}
+		if c.TLSCAFile != "" {
+			if tlsConfig.RootCAs, err = loadCAFile(c.TLSCAFile); err != nil {
+				return "", err
+			}
+		}
+		if err = mysql.RegisterTLSConfig(mysqlTLSConfigName, tlsConfig); err != nil {
+			return "", err
+		}
+		cfg.TLSConfig = mysqlTLSConfigName
+	default:
+		return "", fmt.Errorf("unsupported tls mode for %s: %s", c.StoreType, c.TLSMode)
+	}
+	return cfg.FormatDSN(), nil
+}
+
+func loadCAFile(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// openMySQL opens a pool of MySQL protocol connections with the configured
+// limits.
+func (c *Config) openMySQL(connectionString string) (*sql.DB, error) {
+	db, err := sql.Open("mysql", connectionString)
+	if err != nil {
+		return nil, err
+	}
+	if c.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	return db, nil
+}
+
+// openPostgres opens a pool of Postgres connections with the configured
+// limits.
+func (c *Config) openPostgres(ctx context.Context, connectionString string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	if c.MaxOpenConns > 0 {
+		poolConfig.MaxConns = int32(c.MaxOpenConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		poolConfig.MaxConnLifetime = c.ConnMaxLifetime
+	}
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
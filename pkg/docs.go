@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DocumentFilter selects stored documents by their metadata. Empty fields
+// match every document.
+type DocumentFilter struct {
+	Name          string
+	DocSourceType DocSourceType
+	Model         Model
+}
+
+// metadata returns the filter as the metadata keys Store writes.
+func (f DocumentFilter) metadata() map[string]any {
+	md := make(map[string]any)
+	if f.Name != "" {
+		md["name"] = f.Name
+	}
+	if f.DocSourceType != "" {
+		md["doc_source_type"] = string(f.DocSourceType)
+	}
+	if f.Model != "" {
+		md["model"] = string(f.Model)
+	}
+	return md
+}
+
+// String describes the filter, ie name=post.md doc_type=blog_post.
+func (f DocumentFilter) String() string {
+	parts := make([]string, 0, 3)
+	if f.Name != "" {
+		parts = append(parts, "name="+f.Name)
+	}
+	if f.DocSourceType != "" {
+		parts = append(parts, "doc_type="+string(f.DocSourceType))
+	}
+	if f.Model != "" {
+		parts = append(parts, "model="+string(f.Model))
+	}
+	return strings.Join(parts, " ")
+}
+
+// StoredDocument is a file stored in the vector store, and the chunks it was
+// split into. IngestedAt is zero for documents stored before it was recorded.
+type StoredDocument struct {
+	Name          string        `json:"name"`
+	DocSourceType DocSourceType `json:"doc_source_type"`
+	MD5           string        `json:"md5"`
+	Runner        Runner        `json:"runner"`
+	Model         Model         `json:"model"`
+	Chunks        int           `json:"chunks"`
+	Characters    int           `json:"characters"`
+	IngestedAt    time.Time     `json:"ingested_at"`
+}
+
+// DocumentStats aggregates the documents in the vector store.
+type DocumentStats struct {
+	Documents         int            `json:"documents"`
+	Chunks            int            `json:"chunks"`
+	AverageChunkChars float64        `json:"average_chunk_chars"`
+	ChunksPerDocType  map[string]int `json:"chunks_per_doc_type"`
+	Models            []string       `json:"models"`
+}
+
+func (b *bloggerImpl) Documents(ctx context.Context, filter DocumentFilter) ([]StoredDocument, error) {
+	return b.s.Documents(ctx, filter.metadata())
+}
+
+func (b *bloggerImpl) DocumentStats(ctx context.Context) (*DocumentStats, error) {
+	docs, err := b.s.Documents(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &DocumentStats{
+		Documents:        len(docs),
+		ChunksPerDocType: make(map[string]int),
+		Models:           make([]string, 0),
+	}
+	characters := 0
+	models := make(map[string]bool)
+	for _, doc := range docs {
+		stats.Chunks += doc.Chunks
+		stats.ChunksPerDocType[string(doc.DocSourceType)] += doc.Chunks
+		characters += doc.Characters
+		model := string(doc.Runner) + "/" + string(doc.Model)
+		if !models[model] {
+			models[model] = true
+			stats.Models = append(stats.Models, model)
+		}
+	}
+	if stats.Chunks > 0 {
+		stats.AverageChunkChars = float64(characters) / float64(stats.Chunks)
+	}
+	sort.Strings(stats.Models)
+	return stats, nil
+}
+
+// DeleteDocuments deletes the chunks of the documents matching filter and
+// returns how many chunks were deleted. If dryRun is true, it only counts the
+// chunks that would be deleted. Dolt stores commit the deletion.
+func (b *bloggerImpl) DeleteDocuments(ctx context.Context, filter DocumentFilter, dryRun bool) (int, error) {
+	md := filter.metadata()
+	if len(md) == 0 {
+		return 0, errors.New("refusing to delete every document, a name, doc type or model is required")
+	}
+
+	if dryRun {
+		docs, err := b.s.Documents(ctx, md)
+		if err != nil {
+			return 0, err
+		}
+		n := 0
+		for _, doc := range docs {
+			n += doc.Chunks
+		}
+		return n, nil
+	}
+
+	n, err := b.s.Delete(ctx, md)
+	if err != nil {
+		return 0, err
+	}
+	b.logger.Info("deleted documents", zap.String("filter", filter.String()), zap.Int("chunks", n))
+	if n == 0 {
+		return 0, nil
+	}
+	return n, b.commit(ctx, fmt.Sprintf("Delete %d chunks matching %s", n, filter))
+}
+
+// parseIngestedAt parses the ingested_at metadata Store writes, returning the
+// zero time for documents without it.
+func parseIngestedAt(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
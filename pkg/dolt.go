@@ -3,48 +3,304 @@ package pkg
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"regexp"
 
 	_ "github.com/go-sql-driver/mysql"
 
 	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/vectorstores"
+	lgdolt "github.com/tmc/langchaingo/vectorstores/dolt"
 )
 
 type DoltHasableVectorStore struct {
-	db               *sql.DB
-	connectionString string
-	vs               vectorstores.VectorStore
+	db *sql.DB
+	vs vectorstores.VectorStore
 }
 
-func NewDoltHasableVectorStore(s vectorstores.VectorStore, connectionString string) (HasableVectorStore, error) {
-	db, err := sql.Open("mysql", connectionString)
+// NewDoltHasableVectorStore wraps s, using db to query the tables s writes. Close
+// closes db.
+func NewDoltHasableVectorStore(s vectorstores.VectorStore, db *sql.DB) (HasableVectorStore, error) {
+	return &DoltHasableVectorStore{db: db, vs: s}, nil
+}
+
+var _ HasableVectorStore = &DoltHasableVectorStore{}
+
+func (d *DoltHasableVectorStore) Has(ctx context.Context, metadata map[string]any) (bool, error) {
+	where, args := mysqlMetadataWhere("cmetadata", metadata)
+	query := fmt.Sprintf("SELECT COUNT(*) FROM langchain_dolt_embedding WHERE %s", where)
+	var count int
+	err := d.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (d *DoltHasableVectorStore) Sample(ctx context.Context, metadata map[string]any, k int) ([]schema.Document, error) {
+	return mysqlSample(ctx, d.db, "langchain_dolt_embedding", metadata, k)
+}
+
+func (d *DoltHasableVectorStore) Chunks(ctx context.Context, metadata map[string]any) ([]schema.Document, error) {
+	return mysqlChunks(ctx, d.db, "langchain_dolt_embedding", metadata)
+}
+
+func (d *DoltHasableVectorStore) Documents(ctx context.Context, metadata map[string]any) ([]StoredDocument, error) {
+	return mysqlDocuments(ctx, d.db, "langchain_dolt_embedding", metadata)
+}
+
+func (d *DoltHasableVectorStore) Delete(ctx context.Context, metadata map[string]any) (int, error) {
+	return mysqlDelete(ctx, d.db, "langchain_dolt_embedding", metadata)
+}
+
+func (d *DoltHasableVectorStore) Embeddings(ctx context.Context, metadata map[string]any) ([]StoredChunk, error) {
+	return mysqlEmbeddings(ctx, d.db, "langchain_dolt_embedding", "embedding", metadata)
+}
+
+func (d *DoltHasableVectorStore) VectorDimensions(ctx context.Context) (int, error) {
+	return mysqlVectorDimensions(ctx, d.db, "langchain_dolt_embedding", "embedding")
+}
+
+func (d *DoltHasableVectorStore) CreateTables(ctx context.Context) error {
+	if err := mysqlCreateMetadataTable(ctx, d.db); err != nil {
+		return err
+	}
+	if err := mysqlCreateStyleProfilesTable(ctx, d.db); err != nil {
+		return err
+	}
+	return mysqlCreateGenerationsTable(ctx, d.db)
+}
+
+func (d *DoltHasableVectorStore) Metadata(ctx context.Context, name string) (string, bool, error) {
+	return mysqlMetadata(ctx, d.db, name)
+}
+
+func (d *DoltHasableVectorStore) SetMetadata(ctx context.Context, name string, value string) error {
+	return mysqlSetMetadata(ctx, d.db, name, value)
+}
+
+func (d *DoltHasableVectorStore) SaveStyleProfile(ctx context.Context, profile *StyleProfile) error {
+	return mysqlSaveStyleProfile(ctx, d.db, profile)
+}
+
+func (d *DoltHasableVectorStore) StyleProfile(ctx context.Context, name string) (*StyleProfile, error) {
+	return mysqlStyleProfile(ctx, d.db, name)
+}
+
+func (d *DoltHasableVectorStore) StyleProfiles(ctx context.Context) ([]StyleProfile, error) {
+	return mysqlStyleProfiles(ctx, d.db)
+}
+
+func (d *DoltHasableVectorStore) SaveGeneration(ctx context.Context, gen *Generation) error {
+	return mysqlSaveGeneration(ctx, d.db, gen)
+}
+
+func (d *DoltHasableVectorStore) Generation(ctx context.Context, id int64) (*Generation, error) {
+	return mysqlGeneration(ctx, d.db, id)
+}
+
+func (d *DoltHasableVectorStore) Generations(ctx context.Context, limit int) ([]Generation, error) {
+	return mysqlGenerations(ctx, d.db, limit)
+}
+
+var _ VersionedVectorStore = &DoltHasableVectorStore{}
+
+// doltRevisionRegexp matches the branch names, tags, commit hashes and
+// ancestry specs accepted as revisions, so they can be used in AS OF clauses.
+var doltRevisionRegexp = regexp.MustCompile(`^[\w.\-/~^@]+$`)
+
+func (d *DoltHasableVectorStore) Commit(ctx context.Context, message string) (string, error) {
+	var changes int
+	if err := d.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM dolt_status").Scan(&changes); err != nil {
+		return "", err
+	}
+	if changes == 0 {
+		return "", nil
+	}
+	var hash string
+	err := d.db.QueryRowContext(ctx, "CALL DOLT_COMMIT('-A', '-m', ?)", message).Scan(&hash)
+	return hash, err
+}
+
+func (d *DoltHasableVectorStore) Tag(ctx context.Context, name string, revision string) error {
+	_, err := d.db.ExecContext(ctx, "CALL DOLT_TAG(?, ?)", name, revision)
+	return err
+}
+
+func (d *DoltHasableVectorStore) CreateBranch(ctx context.Context, name string, revision string) error {
+	_, err := d.db.ExecContext(ctx, "CALL DOLT_BRANCH(?, ?)", name, revision)
+	return err
+}
+
+func (d *DoltHasableVectorStore) ResolveRevision(ctx context.Context, revision string) (string, error) {
+	var hash string
+	err := d.db.QueryRowContext(ctx, "SELECT HASHOF(?)", revision).Scan(&hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve revision %s: %w", revision, err)
+	}
+	return hash, nil
+}
+
+func (d *DoltHasableVectorStore) Versions(ctx context.Context, limit int) ([]StoreVersion, error) {
+	tags := make(map[string][]string)
+	tagRows, err := d.db.QueryContext(ctx, "SELECT tag_name, tag_hash FROM dolt_tags ORDER BY tag_name")
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var name, hash string
+		if err := tagRows.Scan(&name, &hash); err != nil {
+			return nil, err
+		}
+		tags[hash] = append(tags[hash], name)
+	}
+	if err := tagRows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, "SELECT commit_hash, committer, date, message FROM dolt_log LIMIT ?", limit)
 	if err != nil {
 		return nil, err
 	}
-	return &DoltHasableVectorStore{db: db, connectionString: connectionString, vs: s}, nil
+	defer rows.Close()
+
+	versions := make([]StoreVersion, 0)
+	for rows.Next() {
+		var v StoreVersion
+		if err := rows.Scan(&v.Hash, &v.Committer, &v.Date, &v.Message); err != nil {
+			return nil, err
+		}
+		v.Tags = tags[v.Hash]
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
 }
 
-var _ HasableVectorStore = &DoltHasableVectorStore{}
+func (d *DoltHasableVectorStore) Branches(ctx context.Context) ([]StoreBranch, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT name, hash, latest_commit_date, latest_commit_message FROM dolt_branches ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-func (d *DoltHasableVectorStore) Has(ctx context.Context, metadata map[string]any) (bool, error) {
-	whereQuerys := make([]string, 0)
-	for k, v := range metadata {
-		whereQuerys = append(whereQuerys, fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(langchain_dolt_embedding.cmetadata, '$.%s')) = '%s'", k, v))
+	branches := make([]StoreBranch, 0)
+	for rows.Next() {
+		var b StoreBranch
+		if err := rows.Scan(&b.Name, &b.Hash, &b.Date, &b.Message); err != nil {
+			return nil, err
+		}
+		branches = append(branches, b)
+	}
+	return branches, rows.Err()
+}
+
+func (d *DoltHasableVectorStore) Merge(ctx context.Context, branch string) (string, error) {
+	var hash, message string
+	var fastForward, conflicts int
+	err := d.db.QueryRowContext(ctx, "CALL DOLT_MERGE(?)", branch).Scan(&hash, &fastForward, &conflicts, &message)
+	if err != nil {
+		return "", err
 	}
-	whereQuery := strings.Join(whereQuerys, " AND ")
-	if len(whereQuery) == 0 {
-		whereQuery = "TRUE"
+	if conflicts > 0 {
+		return "", fmt.Errorf("merging %s has %d conflicts: %s", branch, conflicts, message)
 	}
+	return hash, nil
+}
 
-	query := fmt.Sprintf("SELECT COUNT(*) FROM langchain_dolt_embedding WHERE %s", whereQuery)
-	var count int
-	err := d.db.QueryRowContext(ctx, query).Scan(&count)
+func (d *DoltHasableVectorStore) ChunkCounts(ctx context.Context, revision string) ([]ChunkCount, error) {
+	if !doltRevisionRegexp.MatchString(revision) {
+		return nil, fmt.Errorf("invalid revision: %s", revision)
+	}
+	query := fmt.Sprintf(`SELECT doc_source_type, model, COUNT(*) FROM (
+    SELECT
+        COALESCE(JSON_UNQUOTE(JSON_EXTRACT(cmetadata, '$.doc_source_type')), '') AS doc_source_type,
+        COALESCE(JSON_UNQUOTE(JSON_EXTRACT(cmetadata, '$.model')), '') AS model
+    FROM langchain_dolt_embedding AS OF '%s'
+) AS chunks
+GROUP BY doc_source_type, model
+ORDER BY doc_source_type, model`, revision)
+	rows, err := d.db.QueryContext(ctx, query)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return count > 0, nil
+	defer rows.Close()
+
+	counts := make([]ChunkCount, 0)
+	for rows.Next() {
+		var c ChunkCount
+		if err := rows.Scan(&c.DocSourceType, &c.Model, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+func (d *DoltHasableVectorStore) DiffChunks(ctx context.Context, from string, to string) (int, int, error) {
+	var added, removed sql.NullInt64
+	err := d.db.QueryRowContext(ctx, "SELECT SUM(rows_added), SUM(rows_deleted) FROM DOLT_DIFF_STAT(?, ?, 'langchain_dolt_embedding')", from, to).Scan(&added, &removed)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(added.Int64), int(removed.Int64), nil
+}
+
+func (d *DoltHasableVectorStore) SimilaritySearchAsOf(ctx context.Context, embedding []float32, k int, revision string) ([]schema.Document, error) {
+	if !doltRevisionRegexp.MatchString(revision) {
+		return nil, fmt.Errorf("invalid revision: %s", revision)
+	}
+	jsonEmbedding, err := json.Marshal(embedding)
+	if err != nil {
+		return nil, err
+	}
+
+	// mirrors the langchaingo dolt store's search, which only supports
+	// euclidean squared distance.
+	query := fmt.Sprintf(`SELECT
+    data.document,
+    data.cmetadata,
+    (1 - data.distance) AS score
+FROM
+(
+    SELECT
+        f.document,
+        f.cmetadata,
+        VEC_DISTANCE(f.embedding, ?) AS distance
+    FROM
+        (SELECT * FROM langchain_dolt_embedding AS OF '%[1]s' WHERE JSON_LENGTH(embedding) = ?) AS f
+        JOIN langchain_dolt_collection AS OF '%[1]s' AS t ON f.collection_id = t.uuid
+    WHERE
+        t.name = ?
+) AS data
+ORDER BY
+    data.distance
+    LIMIT ?`, revision)
+
+	rows, err := d.db.QueryContext(ctx, query, string(jsonEmbedding), len(embedding), lgdolt.DefaultDatabaseName, k)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := make([]schema.Document, 0)
+	for rows.Next() {
+		var content string
+		var metadata []byte
+		var score float64
+		if err := rows.Scan(&content, &metadata, &score); err != nil {
+			return nil, err
+		}
+		doc := schema.Document{PageContent: content, Score: float32(score)}
+		if len(metadata) > 0 {
+			if err := json.Unmarshal(metadata, &doc.Metadata); err != nil {
+				return nil, err
+			}
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
 }
 
 func (d *DoltHasableVectorStore) AddDocuments(ctx context.Context, documents []schema.Document, opts ...vectorstores.Option) ([]string, error) {
@@ -58,3 +314,27 @@ func (d *DoltHasableVectorStore) SimilaritySearch(ctx context.Context, query str
 func (d *DoltHasableVectorStore) Close() error {
 	return d.db.Close()
 }
+
+// ensureDoltBranch creates branch from the default branch of the config's
+// database if it does not exist.
+func ensureDoltBranch(ctx context.Context, config *Config, branch string) error {
+	connectionString, err := config.connectionString(config.StoreName)
+	if err != nil {
+		return err
+	}
+	db, err := config.openMySQL(connectionString)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var exists int
+	if err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM dolt_branches WHERE name = ?", branch).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return nil
+	}
+	_, err = db.ExecContext(ctx, "CALL DOLT_BRANCH(?)", branch)
+	return err
+}
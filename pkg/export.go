@@ -0,0 +1,212 @@
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"go.uber.org/zap"
+)
+
+// maxExportLineSize is the longest line Import reads, enough for a chunk of
+// a few thousand tokens and a vector of a few thousand dimensions.
+const maxExportLineSize = 16 * 1024 * 1024
+
+// StoredChunk is a stored chunk and its embedding. Export writes one per line
+// as JSON.
+type StoredChunk struct {
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata"`
+	Vector   []float32      `json:"vector"`
+}
+
+// Export writes every chunk of the stored documents matching filter to w as
+// JSON lines, with its metadata and vector. It returns the number of chunks
+// written.
+func (b *bloggerImpl) Export(ctx context.Context, w io.Writer, filter DocumentFilter) (int, error) {
+	docs, err := b.s.Documents(ctx, filter.metadata())
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	n := 0
+	for _, doc := range docs {
+		chunks, err := b.s.Embeddings(ctx, storedDocumentMetadata(doc))
+		if err != nil {
+			return n, err
+		}
+		for _, chunk := range chunks {
+			if err = enc.Encode(chunk); err != nil {
+				return n, err
+			}
+			n++
+		}
+		b.logger.Info("exported document", zap.String("name", doc.Name), zap.Int("chunks", len(chunks)))
+	}
+	return n, bw.Flush()
+}
+
+// Import adds the chunks Export wrote to r to the store, using their vectors
+// instead of embedding them again. The chunks must have been embedded with
+// the store's runner and model. Documents the store already has are skipped.
+// It returns the number of chunks added.
+func (b *bloggerImpl) Import(ctx context.Context, r io.Reader) (int, error) {
+	n, err := b.importChunks(ctx, r)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	return n, b.commit(ctx, fmt.Sprintf("Import %d chunks", n))
+}
+
+// importChunks adds chunks to the store, checking them against the store's
+// runner, model and vector dimensions without calling the embedder. If the
+// store has no recorded dimensions, those of the chunks are recorded.
+func (b *bloggerImpl) importChunks(ctx context.Context, r io.Reader) (int, error) {
+	dims, recorded, err := storedVectorDimensions(ctx, b.s)
+	if err != nil {
+		return 0, err
+	}
+	if dims == 0 {
+		// the dimensions a MariaDB or Postgres store was created with.
+		b.dimensions.mu.Lock()
+		dims = b.dimensions.dims
+		b.dimensions.mu.Unlock()
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxExportLineSize)
+
+	n := 0
+	batch := make([]StoredChunk, 0)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		added, err := b.importDocument(ctx, batch)
+		if err != nil {
+			return err
+		}
+		n += added
+		batch = batch[:0]
+		return nil
+	}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		var chunk StoredChunk
+		if err = json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			return n, fmt.Errorf("line %d: %w", line, err)
+		}
+		runner, model := fmt.Sprint(chunk.Metadata["runner"]), fmt.Sprint(chunk.Metadata["model"])
+		if runner != string(b.runner) || model != string(b.model) {
+			return n, fmt.Errorf("line %d: chunk was embedded with %s/%s, but the store uses %s/%s", line, runner, model, b.runner, b.model)
+		}
+		if dims == 0 {
+			dims = len(chunk.Vector)
+		}
+		if len(chunk.Vector) != dims {
+			return n, fmt.Errorf("line %d: vector has %d dimensions, but the store has %d", line, len(chunk.Vector), dims)
+		}
+		// chunks are exported grouped by document, so a change of document
+		// ends a batch.
+		if len(batch) > 0 && !sameDocument(batch[0].Metadata, chunk.Metadata) {
+			if err = flush(); err != nil {
+				return n, err
+			}
+		}
+		batch = append(batch, chunk)
+	}
+	if err = scanner.Err(); err != nil {
+		return n, err
+	}
+	if err = flush(); err != nil {
+		return n, err
+	}
+	if !recorded && n > 0 {
+		return n, b.s.SetMetadata(ctx, vectorDimensionsKey, strconv.Itoa(dims))
+	}
+	return n, nil
+}
+
+func (b *bloggerImpl) importDocument(ctx context.Context, chunks []StoredChunk) (int, error) {
+	md := documentMetadata(chunks[0].Metadata)
+	has, err := b.s.Has(ctx, md)
+	if err != nil {
+		return 0, err
+	}
+	if has {
+		b.logger.Info("document already exists", zap.Any("name", md["name"]))
+		return 0, nil
+	}
+
+	docs := make([]schema.Document, 0, len(chunks))
+	vectors := make([][]float32, 0, len(chunks))
+	for _, chunk := range chunks {
+		docs = append(docs, schema.Document{PageContent: chunk.Content, Metadata: chunk.Metadata})
+		vectors = append(vectors, chunk.Vector)
+	}
+	if _, err = b.s.AddDocuments(ctx, docs, vectorstores.WithEmbedder(precomputedEmbedder(vectors))); err != nil {
+		return 0, err
+	}
+	b.logger.Info("imported document", zap.Any("name", md["name"]), zap.Int("chunks", len(docs)))
+	return len(docs), nil
+}
+
+// documentKeys are the metadata keys Store writes that identify a document.
+var documentKeys = []string{"doc_source_type", "name", "runner", "model", "md5"}
+
+// documentMetadata returns the keys of a chunk's metadata that identify the
+// document it was split from.
+func documentMetadata(metadata map[string]any) map[string]any {
+	md := make(map[string]any, len(documentKeys))
+	for _, k := range documentKeys {
+		if v, ok := metadata[k]; ok {
+			md[k] = v
+		}
+	}
+	return md
+}
+
+func storedDocumentMetadata(doc StoredDocument) map[string]any {
+	return map[string]any{
+		"doc_source_type": string(doc.DocSourceType),
+		"name":            doc.Name,
+		"runner":          string(doc.Runner),
+		"model":           string(doc.Model),
+		"md5":             doc.MD5,
+	}
+}
+
+func sameDocument(a, b map[string]any) bool {
+	for _, k := range documentKeys {
+		if fmt.Sprint(a[k]) != fmt.Sprint(b[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+// precomputedEmbedder returns vectors that were already computed, so that
+// chunks can be added to a store without embedding them again. It must be
+// used for a single AddDocuments call with the documents the vectors belong
+// to, in order.
+type precomputedEmbedder [][]float32
+
+func (e precomputedEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) != len(e) {
+		return nil, fmt.Errorf("have %d vectors for %d documents", len(e), len(texts))
+	}
+	return e, nil
+}
+
+func (e precomputedEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return nil, fmt.Errorf("precomputed embedder cannot embed queries")
+}
@@ -0,0 +1,162 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func exportedChunks(t *testing.T, chunks ...StoredChunk) string {
+	t.Helper()
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	for _, chunk := range chunks {
+		if err := enc.Encode(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sb.String()
+}
+
+func testChunk(name string, runner Runner, model Model, vector ...float32) StoredChunk {
+	return StoredChunk{
+		Content: "content of " + name,
+		Metadata: map[string]any{
+			"doc_source_type": "blog_post",
+			"name":            name,
+			"runner":          string(runner),
+			"model":           string(model),
+			"md5":             "md5 of " + name,
+		},
+		Vector: vector,
+	}
+}
+
+func TestImportChunks(t *testing.T) {
+	tests := []struct {
+		name string
+		// recorded are the store's recorded dimensions, if not empty.
+		recorded string
+		// opened are the dimensions the store was opened with.
+		opened   int
+		existing []StoredChunk
+		chunks   []StoredChunk
+		added    int
+		err      string
+		expected string
+	}{
+		{
+			name:     "records the dimensions of a new store",
+			chunks:   []StoredChunk{testChunk("a.md", OllamaRunner, "model", 1, 2), testChunk("b.md", OllamaRunner, "model", 3, 4)},
+			added:    2,
+			expected: "2",
+		},
+		{
+			name:     "checks against the recorded dimensions",
+			recorded: "3",
+			chunks:   []StoredChunk{testChunk("a.md", OllamaRunner, "model", 1, 2)},
+			err:      "vector has 2 dimensions, but the store has 3",
+			expected: "3",
+		},
+		{
+			name:   "checks against the dimensions the store was opened with",
+			opened: 3,
+			chunks: []StoredChunk{testChunk("a.md", OllamaRunner, "model", 1, 2)},
+			err:    "vector has 2 dimensions, but the store has 3",
+		},
+		{
+			name:     "checks against the stored vectors",
+			existing: []StoredChunk{testChunk("a.md", OllamaRunner, "model", 1, 2, 3)},
+			chunks:   []StoredChunk{testChunk("b.md", OllamaRunner, "model", 1, 2)},
+			err:      "vector has 2 dimensions, but the store has 3",
+		},
+		{
+			name:   "checks the chunks against each other",
+			chunks: []StoredChunk{testChunk("a.md", OllamaRunner, "model", 1, 2), testChunk("b.md", OllamaRunner, "model", 1, 2, 3)},
+			err:    "line 2: vector has 3 dimensions, but the store has 2",
+		},
+		{
+			name:   "rejects another model",
+			chunks: []StoredChunk{testChunk("a.md", OllamaRunner, "other", 1, 2)},
+			err:    "chunk was embedded with ollama/other, but the store uses ollama/model",
+		},
+		{
+			name:   "rejects another runner",
+			chunks: []StoredChunk{testChunk("a.md", OpenAIRunner, "model", 1, 2)},
+			err:    "chunk was embedded with openai/model, but the store uses ollama/model",
+		},
+		{
+			name:     "skips stored documents",
+			recorded: "2",
+			existing: []StoredChunk{testChunk("a.md", OllamaRunner, "model", 1, 2)},
+			chunks:   []StoredChunk{testChunk("a.md", OllamaRunner, "model", 1, 2), testChunk("b.md", OllamaRunner, "model", 1, 2)},
+			added:    1,
+			expected: "2",
+		},
+	}
+	for _, test := range tests {
+		s := newMemoryStore()
+		s.chunks = test.existing
+		if test.recorded != "" {
+			s.metadata[vectorDimensionsKey] = test.recorded
+		}
+		b := &bloggerImpl{
+			s:          s,
+			runner:     OllamaRunner,
+			model:      "model",
+			dimensions: &dimensionsCheck{dims: test.opened},
+			logger:     zap.NewNop(),
+		}
+
+		added, err := b.importChunks(context.Background(), strings.NewReader(exportedChunks(t, test.chunks...)))
+		if test.err == "" && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.name, test.err, err)
+		}
+		if added != test.added {
+			t.Errorf("%s: expected %d chunks added, got %d", test.name, test.added, added)
+		}
+		if recorded := s.metadata[vectorDimensionsKey]; recorded != test.expected {
+			t.Errorf("%s: expected recorded dimensions %q, got %q", test.name, test.expected, recorded)
+		}
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := newMemoryStore()
+	source.chunks = []StoredChunk{
+		testChunk("a.md", OllamaRunner, "model", 1, 2),
+		testChunk("a.md", OllamaRunner, "model", 3, 4),
+		testChunk("b.md", OllamaRunner, "model", 5, 6),
+	}
+	source.chunks[1].Content = "second chunk of a.md"
+	b := &bloggerImpl{s: source, runner: OllamaRunner, model: "model", dimensions: &dimensionsCheck{}, logger: zap.NewNop()}
+
+	var sb strings.Builder
+	n, err := b.Export(context.Background(), &sb, DocumentFilter{Name: "a.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 chunks exported, got %d", n)
+	}
+
+	target := newMemoryStore()
+	b.s = target
+	if n, err = b.Import(context.Background(), strings.NewReader(sb.String())); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(target.chunks) != 2 {
+		t.Fatalf("expected 2 chunks imported, got %d", n)
+	}
+	for i, chunk := range target.chunks {
+		if chunk.Content != source.chunks[i].Content || len(chunk.Vector) != 2 || chunk.Vector[0] != source.chunks[i].Vector[0] {
+			t.Errorf("chunk %d: expected %+v, got %+v", i, source.chunks[i], chunk)
+		}
+	}
+}
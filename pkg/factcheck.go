@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	factCheckNumSearchDocs = 4
+	maxFactCheckClaims     = 30
+)
+
+// ClaimCheck is the verdict for a single claim extracted from a draft.
+type ClaimCheck struct {
+	Claim       string
+	Supported   bool
+	Explanation string
+	Sources     []string
+}
+
+// FactCheckReport is the result of checking a draft against the vector store.
+type FactCheckReport struct {
+	Claims         []ClaimCheck
+	RevisedContent string
+}
+
+func (r *FactCheckReport) NumUnsupported() int {
+	n := 0
+	for _, c := range r.Claims {
+		if !c.Supported {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *FactCheckReport) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d claims checked, %d unsupported\n", len(r.Claims), r.NumUnsupported()))
+	for i, c := range r.Claims {
+		verdict := "SUPPORTED"
+		if !c.Supported {
+			verdict = "UNSUPPORTED"
+		}
+		sb.WriteString(fmt.Sprintf("\n%d. [%s] %s\n", i+1, verdict, c.Claim))
+		if c.Explanation != "" {
+			sb.WriteString(fmt.Sprintf("   %s\n", c.Explanation))
+		}
+		for _, source := range c.Sources {
+			sb.WriteString(fmt.Sprintf("   source: %s\n", source))
+		}
+	}
+	return sb.String()
+}
+
+// FactCheck splits content into claims and asks the llm whether each claim is
+// supported by the documents retrieved for it from the vector store.
+func (b *bloggerImpl) FactCheck(ctx context.Context, content string) (*FactCheckReport, error) {
+	claims, err := b.extractClaims(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FactCheckReport{}
+	for _, claim := range claims {
+		check, err := b.checkClaim(ctx, claim)
+		if err != nil {
+			return nil, err
+		}
+		report.Claims = append(report.Claims, check)
+	}
+	return report, nil
+}
+
+func (b *bloggerImpl) extractClaims(ctx context.Context, content string) ([]string, error) {
+	prompt, err := b.prompts.Messages(FactCheckClaimsPromptTemplate, PromptData{Content: content})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.complete(ctx, prompt, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := parseClaims(resp)
+	if len(claims) > maxFactCheckClaims {
+		claims = claims[:maxFactCheckClaims]
+	}
+	return claims, nil
+}
+
+func (b *bloggerImpl) checkClaim(ctx context.Context, claim string) (ClaimCheck, error) {
+	check := ClaimCheck{Claim: claim}
+
+	docs, err := b.similaritySearch(ctx, claim, factCheckNumSearchDocs)
+	if err != nil {
+		return check, err
+	}
+
+	for _, doc := range docs {
+		if name, ok := doc.Metadata["name"].(string); ok {
+			check.Sources = append(check.Sources, name)
+		}
+	}
+
+	prompt, err := b.prompts.Messages(FactCheckVerifyPromptTemplate, PromptData{
+		Claim:   claim,
+		Context: formatContext(docs),
+	})
+	if err != nil {
+		return check, err
+	}
+
+	resp, err := b.complete(ctx, prompt, 0)
+	if err != nil {
+		return check, err
+	}
+
+	check.Supported, check.Explanation = parseVerdict(resp)
+	return check, nil
+}
+
+func (b *bloggerImpl) reviseUnsupportedClaims(ctx context.Context, content string, report *FactCheckReport) (string, error) {
+	unsupported := make([]string, 0)
+	for _, c := range report.Claims {
+		if !c.Supported {
+			unsupported = append(unsupported, c.Claim)
+		}
+	}
+
+	prompt, err := b.prompts.Messages(FactCheckRevisePromptTemplate, PromptData{
+		Content: content,
+		Claims:  unsupported,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return b.complete(ctx, prompt, 0.3)
+}
+
+var listMarkerRegexp = regexp.MustCompile(`^([-*+]|\d+[.)])\s+`)
+
+// parseClaims reads one claim per line from an llm response, dropping list
+// markers, headings and code fences.
+func parseClaims(resp string) []string {
+	claims := make([]string, 0)
+	for _, line := range strings.Split(resp, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "```") {
+			continue
+		}
+		line = strings.TrimSpace(listMarkerRegexp.ReplaceAllString(line, ""))
+		if line != "" {
+			claims = append(claims, line)
+		}
+	}
+	return claims
+}
+
+// parseVerdict expects the first non-empty line of resp to be SUPPORTED or
+// UNSUPPORTED and treats the rest as the explanation. Anything that is not
+// clearly supported is reported as unsupported.
+func parseVerdict(resp string) (bool, string) {
+	resp = strings.TrimSpace(resp)
+	verdict, explanation, _ := strings.Cut(resp, "\n")
+	verdict = strings.ToUpper(strings.Trim(verdict, "*#: "))
+	supported := strings.HasPrefix(verdict, "SUPPORTED")
+	return supported, strings.TrimSpace(explanation)
+}
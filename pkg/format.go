@@ -0,0 +1,308 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultOutputFormat = "blog"
+
+	maxTweetLength    = 280
+	maxLinkedInLength = 3000
+
+	tweetSeparator = "---"
+)
+
+// OutputFormat describes a kind of content Generate can produce.
+type OutputFormat struct {
+	Name    string
+	Aliases []string
+
+	// Prompt is given to the llm as the requested output format.
+	Prompt string
+
+	// DefaultLength is used when Generate is called with a length of zero.
+	DefaultLength int
+
+	// Validate returns a description of each way content fails to match the
+	// format's structure. It may be nil.
+	Validate func(content string) []string
+
+	// Render turns llm output into content ready to publish. It may be nil.
+	Render func(content string) string
+}
+
+var outputFormats = make(map[string]*OutputFormat)
+
+// RegisterOutputFormat adds f to the registry of output formats, replacing any
+// format with the same name. Names and aliases are case insensitive. It panics
+// if f's name or one of its aliases belongs to another format.
+func RegisterOutputFormat(f *OutputFormat) {
+	name := strings.ToLower(f.Name)
+	keys := append([]string{name}, f.Aliases...)
+	for i, key := range keys {
+		key = strings.ToLower(key)
+		if existing, ok := outputFormats[key]; ok && strings.ToLower(existing.Name) != name {
+			panic(fmt.Sprintf("output format %s: %s is already registered by %s", f.Name, key, existing.Name))
+		}
+		keys[i] = key
+	}
+
+	for key, existing := range outputFormats {
+		if strings.ToLower(existing.Name) == name {
+			delete(outputFormats, key)
+		}
+	}
+	for _, key := range keys {
+		outputFormats[key] = f
+	}
+}
+
+// GetOutputFormat returns the registered output format with the given name or alias.
+func GetOutputFormat(name string) (*OutputFormat, error) {
+	if name == "" {
+		name = DefaultOutputFormat
+	}
+	f, ok := outputFormats[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output format: %s, must be one of: %s", name, strings.Join(OutputFormatNames(), ", "))
+	}
+	return f, nil
+}
+
+// OutputFormatNames returns the names of the registered output formats, without aliases.
+func OutputFormatNames() []string {
+	names := make([]string, 0, len(outputFormats))
+	for key, f := range outputFormats {
+		if key == strings.ToLower(f.Name) {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *OutputFormat) validate(content string) []string {
+	if f.Validate == nil {
+		return nil
+	}
+	return f.Validate(content)
+}
+
+func (f *OutputFormat) render(content string) string {
+	if f.Render == nil {
+		return strings.TrimSpace(content)
+	}
+	return f.Render(content)
+}
+
+func init() {
+	RegisterOutputFormat(&OutputFormat{
+		Name:    "blog",
+		Aliases: []string{"blog-post", "markdown"},
+		Prompt: `A blog post written in markdown. Start with a level one heading containing the title, ` +
+			`and use level two headings for sections. Use fenced code blocks for any code or commands.`,
+		DefaultLength: 1000,
+		Validate:      validateBlog,
+	})
+	RegisterOutputFormat(&OutputFormat{
+		Name: "email",
+		Prompt: `A marketing email. The first line must be the subject line, written as "Subject: <subject>", ` +
+			`followed by a blank line and the body. Keep paragraphs short and end with a single call to action.`,
+		DefaultLength: 300,
+		Validate:      validateEmail,
+		Render:        renderEmail,
+	})
+	RegisterOutputFormat(&OutputFormat{
+		Name:    "tweet-thread",
+		Aliases: []string{"tweet", "twitter", "x"},
+		Prompt: fmt.Sprintf(`A thread of tweets. Separate tweets with a line containing only "%s". `+
+			`Each tweet must be at most %d characters including its "1/N" numbering, which will be added for you. `+
+			`Do not use markdown.`, tweetSeparator, maxTweetLength-8),
+		DefaultLength: 200,
+		Validate:      validateTweetThread,
+		Render:        renderTweetThread,
+	})
+	RegisterOutputFormat(&OutputFormat{
+		Name: "linkedin",
+		Prompt: fmt.Sprintf(`A LinkedIn post of at most %d characters. Open with a hook in the first line, `+
+			`use short paragraphs, and end with up to three hashtags. Do not use markdown.`, maxLinkedInLength),
+		DefaultLength: 250,
+		Validate:      validateLinkedIn,
+		Render:        stripMarkdown,
+	})
+	RegisterOutputFormat(&OutputFormat{
+		Name: "release-notes",
+		Prompt: `Release notes written in markdown. Start with a level one heading containing the release name, ` +
+			`then use level two headings such as "Features", "Improvements" and "Bug Fixes", ` +
+			`each followed by a bulleted list of changes.`,
+		DefaultLength: 400,
+		Validate:      validateReleaseNotes,
+	})
+}
+
+func validateBlog(content string) []string {
+	if !strings.HasPrefix(firstLine(content), "# ") {
+		return []string{"blog post does not start with a title heading"}
+	}
+	return nil
+}
+
+func validateEmail(content string) []string {
+	subject, ok := emailSubject(content)
+	if !ok {
+		return []string{`email does not start with a "Subject:" line`}
+	}
+	if subject == "" {
+		return []string{"email subject is empty"}
+	}
+	return nil
+}
+
+// emailSubject returns the subject from an email's leading "Subject:" line.
+func emailSubject(content string) (string, bool) {
+	line := firstLine(content)
+	prefix := "subject:"
+	if !strings.HasPrefix(strings.ToLower(line), prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(line[len(prefix):]), true
+}
+
+func renderEmail(content string) string {
+	content = strings.TrimSpace(content)
+	subject, ok := emailSubject(content)
+	if !ok {
+		return content
+	}
+	_, body, _ := strings.Cut(content, "\n")
+	return fmt.Sprintf("Subject: %s\n\n%s", subject, strings.TrimSpace(body))
+}
+
+func validateTweetThread(content string) []string {
+	problems := make([]string, 0)
+	for i, tweet := range splitRenderedTweets(renderTweetThread(content)) {
+		if n := len([]rune(tweet)); n > maxTweetLength {
+			problems = append(problems, fmt.Sprintf("tweet %d is %d characters, more than %d", i+1, n, maxTweetLength))
+		}
+	}
+	return problems
+}
+
+var tweetNumberRegexp = regexp.MustCompile(`^\d+\s*/\s*\d*\s*`)
+
+func splitTweets(content string) []string {
+	tweets := make([]string, 0)
+	var sb strings.Builder
+	flush := func() {
+		tweet := strings.TrimSpace(sb.String())
+		tweet = strings.TrimSpace(tweetNumberRegexp.ReplaceAllString(tweet, ""))
+		if tweet != "" {
+			tweets = append(tweets, tweet)
+		}
+		sb.Reset()
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == tweetSeparator {
+			flush()
+			continue
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	flush()
+	return tweets
+}
+
+func renderTweetThread(content string) string {
+	tweets := splitTweets(content)
+	for i, tweet := range tweets {
+		tweets[i] = fmt.Sprintf("%d/%d %s", i+1, len(tweets), tweet)
+	}
+	return strings.Join(tweets, "\n\n")
+}
+
+var renderedTweetNumberRegexp = regexp.MustCompile(`(?m)^(\d+)/(\d+) `)
+
+// splitRenderedTweets splits a thread rendered by renderTweetThread at its
+// "i/n " numbering markers, so tweets containing blank lines stay whole. A
+// marker is only a split if it is the next number of the thread.
+func splitRenderedTweets(thread string) []string {
+	starts := make([]int, 0)
+	total := ""
+	for _, m := range renderedTweetNumberRegexp.FindAllStringSubmatchIndex(thread, -1) {
+		number, count := thread[m[2]:m[3]], thread[m[4]:m[5]]
+		if len(starts) == 0 {
+			total = count
+		}
+		if number != strconv.Itoa(len(starts)+1) || count != total {
+			continue
+		}
+		starts = append(starts, m[0])
+	}
+	if len(starts) == 0 {
+		return []string{thread}
+	}
+
+	tweets := make([]string, 0, len(starts))
+	for i, start := range starts {
+		end := len(thread)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		tweets = append(tweets, strings.TrimSpace(thread[start:end]))
+	}
+	return tweets
+}
+
+func validateLinkedIn(content string) []string {
+	if n := len([]rune(stripMarkdown(content))); n > maxLinkedInLength {
+		return []string{fmt.Sprintf("linkedin post is %d characters, more than %d", n, maxLinkedInLength)}
+	}
+	return nil
+}
+
+var markdownHeadingRegexp = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+
+// stripMarkdown removes the markdown syntax that plain text platforms would
+// otherwise show literally.
+func stripMarkdown(content string) string {
+	content = markdownHeadingRegexp.ReplaceAllString(content, "")
+	content = strings.ReplaceAll(content, "**", "")
+	content = strings.ReplaceAll(content, "__", "")
+	return strings.TrimSpace(content)
+}
+
+func validateReleaseNotes(content string) []string {
+	problems := make([]string, 0)
+	if !strings.HasPrefix(firstLine(content), "# ") {
+		problems = append(problems, "release notes do not start with a release heading")
+	}
+	hasSection := false
+	hasBullet := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "## ") {
+			hasSection = true
+		}
+		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
+			hasBullet = true
+		}
+	}
+	if !hasSection {
+		problems = append(problems, "release notes have no sections")
+	}
+	if !hasBullet {
+		problems = append(problems, "release notes have no bulleted changes")
+	}
+	return problems
+}
+
+func firstLine(content string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	return strings.TrimSpace(line)
+}
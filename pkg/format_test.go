@@ -0,0 +1,163 @@
+package pkg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetOutputFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"", DefaultOutputFormat},
+		{"blog", "blog"},
+		{"Markdown", "blog"},
+		{"TWEET", "tweet-thread"},
+		{"x", "tweet-thread"},
+		{"release-notes", "release-notes"},
+	}
+	for _, test := range tests {
+		f, err := GetOutputFormat(test.name)
+		if err != nil {
+			t.Errorf("%q: %v", test.name, err)
+			continue
+		}
+		if f.Name != test.expected {
+			t.Errorf("%q: expected %s, got %s", test.name, test.expected, f.Name)
+		}
+	}
+
+	if _, err := GetOutputFormat("haiku"); err == nil {
+		t.Error("expected an error for an unregistered format")
+	}
+}
+
+func TestRegisterOutputFormat(t *testing.T) {
+	defer func(saved map[string]*OutputFormat) { outputFormats = saved }(outputFormats)
+	outputFormats = make(map[string]*OutputFormat)
+
+	RegisterOutputFormat(&OutputFormat{Name: "Press-Release", Aliases: []string{"PR", "announcement"}})
+	for _, name := range []string{"press-release", "PRESS-RELEASE", "pr", "Announcement"} {
+		if _, err := GetOutputFormat(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	if names := OutputFormatNames(); !reflect.DeepEqual(names, []string{"Press-Release"}) {
+		t.Errorf("expected [Press-Release], got %v", names)
+	}
+
+	// replacing a format drops the aliases it no longer has.
+	RegisterOutputFormat(&OutputFormat{Name: "press-release", Aliases: []string{"pr"}})
+	if _, err := GetOutputFormat("announcement"); err == nil {
+		t.Error("expected the replaced format's alias to be dropped")
+	}
+
+	tests := []*OutputFormat{
+		{Name: "public-relations", Aliases: []string{"Pr"}},
+		{Name: "PR"},
+	}
+	for _, f := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic for a name registered by another format", f.Name)
+				}
+			}()
+			RegisterOutputFormat(f)
+		}()
+	}
+}
+
+func TestSplitTweets(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected []string
+	}{
+		{"one tweet", []string{"one tweet"}},
+		{fmt.Sprintf("first\n%s\nsecond", tweetSeparator), []string{"first", "second"}},
+		{fmt.Sprintf("1/2 first\n%s\n2/2 second", tweetSeparator), []string{"first", "second"}},
+		{fmt.Sprintf("first\n\nstill first\n%s\n\n%s\nsecond", tweetSeparator, tweetSeparator), []string{"first\n\nstill first", "second"}},
+	}
+	for _, test := range tests {
+		if tweets := splitTweets(test.content); !reflect.DeepEqual(tweets, test.expected) {
+			t.Errorf("%q: expected %q, got %q", test.content, test.expected, tweets)
+		}
+	}
+}
+
+func TestSplitRenderedTweets(t *testing.T) {
+	tests := []struct {
+		thread   string
+		expected []string
+	}{
+		{"no numbering", []string{"no numbering"}},
+		{"1/2 first\n\n2/2 second", []string{"1/2 first", "2/2 second"}},
+		{"1/2 first\n\nwith a blank line\n\n2/2 second", []string{"1/2 first\n\nwith a blank line", "2/2 second"}},
+		{"1/2 first\n3/4 is not a marker\n\n2/2 second", []string{"1/2 first\n3/4 is not a marker", "2/2 second"}},
+	}
+	for _, test := range tests {
+		if tweets := splitRenderedTweets(test.thread); !reflect.DeepEqual(tweets, test.expected) {
+			t.Errorf("%q: expected %q, got %q", test.thread, test.expected, tweets)
+		}
+	}
+
+	content := fmt.Sprintf("first\n\nstill first\n%s\nsecond", tweetSeparator)
+	if tweets := splitRenderedTweets(renderTweetThread(content)); len(tweets) != 2 {
+		t.Errorf("expected a rendered thread to split back into 2 tweets, got %q", tweets)
+	}
+}
+
+func TestValidateOutputFormats(t *testing.T) {
+	long := strings.Repeat("a", maxTweetLength)
+	tests := []struct {
+		format   string
+		content  string
+		problems int
+	}{
+		{"blog", "# Title\n\nBody", 0},
+		{"blog", "Title\n\nBody", 1},
+		{"email", "Subject: Hello\n\nBody", 0},
+		{"email", "subject:\n\nBody", 1},
+		{"email", "Hello\n\nBody", 1},
+		{"tweet-thread", fmt.Sprintf("short\n%s\nshort", tweetSeparator), 0},
+		{"tweet-thread", fmt.Sprintf("short\n%s\n%s", tweetSeparator, long), 1},
+		{"linkedin", "## " + strings.Repeat("a", maxLinkedInLength), 0},
+		{"linkedin", strings.Repeat("a", maxLinkedInLength+1), 1},
+		{"release-notes", "# v1.0\n\n## Features\n\n- a feature", 0},
+		{"release-notes", "v1.0\n\nnothing", 3},
+	}
+	for _, test := range tests {
+		f, err := GetOutputFormat(test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if problems := f.validate(test.content); len(problems) != test.problems {
+			t.Errorf("%s %q: expected %d problems, got %q", test.format, test.content, test.problems, problems)
+		}
+	}
+}
+
+func TestRenderOutputFormats(t *testing.T) {
+	tests := []struct {
+		format   string
+		content  string
+		expected string
+	}{
+		{"blog", "  # Title\n\nBody\n", "# Title\n\nBody"},
+		{"email", "subject:  Hello\nBody", "Subject: Hello\n\nBody"},
+		{"tweet-thread", fmt.Sprintf("first\n%s\n2/2 second", tweetSeparator), "1/2 first\n\n2/2 second"},
+		{"linkedin", "## **Bold** move\n\n#dolt", "Bold move\n\n#dolt"},
+	}
+	for _, test := range tests {
+		f, err := GetOutputFormat(test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rendered := f.render(test.content); rendered != test.expected {
+			t.Errorf("%s %q: expected %q, got %q", test.format, test.content, test.expected, rendered)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+)
+
+const generationsTableName = "robot_blogger_generations"
+
+var ErrGenerationNotFound = errors.New("generation not found")
+
+// RetrievedDocument identifies a stored chunk retrieved as context for a
+// generation, by the name and md5 of the file it was split from.
+type RetrievedDocument struct {
+	Name          string  `json:"name"`
+	MD5           string  `json:"md5"`
+	DocSourceType string  `json:"doc_source_type"`
+	Score         float32 `json:"score"`
+}
+
+// TokenUsage is the number of tokens the llm reported using.
+type TokenUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// generationRun collects the documents retrieved and the tokens used while
+// a Generate call runs, so they can be recorded with its Generation.
+type generationRun struct {
+	documents []RetrievedDocument
+	usage     TokenUsage
+	retrieval time.Duration
+}
+
+type generationRunKey struct{}
+
+// withGenerationRun returns a context carrying a new run, so that concurrent
+// Generate calls each record their own documents and usage.
+func withGenerationRun(ctx context.Context) (context.Context, *generationRun) {
+	run := &generationRun{}
+	return context.WithValue(ctx, generationRunKey{}, run), run
+}
+
+// currentRun returns the run ctx carries, or nil outside of Generate.
+func currentRun(ctx context.Context) *generationRun {
+	run, _ := ctx.Value(generationRunKey{}).(*generationRun)
+	return run
+}
+
+// recordDocuments adds docs, retrieved in duration, to the run ctx carries.
+func recordDocuments(ctx context.Context, docs []schema.Document, duration time.Duration) {
+	run := currentRun(ctx)
+	if run == nil {
+		return
+	}
+	for _, doc := range docs {
+		run.documents = append(run.documents, RetrievedDocument{
+			Name:          fmt.Sprint(doc.Metadata["name"]),
+			MD5:           fmt.Sprint(doc.Metadata["md5"]),
+			DocSourceType: fmt.Sprint(doc.Metadata["doc_source_type"]),
+			Score:         doc.Score,
+		})
+	}
+	run.retrieval += duration
+}
+
+// recordUsage adds the tokens reported in resp to the run ctx carries.
+func recordUsage(ctx context.Context, resp *llms.ContentResponse) {
+	run := currentRun(ctx)
+	if run == nil || resp == nil {
+		return
+	}
+	for _, choice := range resp.Choices {
+		run.usage.PromptTokens += generationInfoInt(choice.GenerationInfo, "PromptTokens")
+		run.usage.CompletionTokens += generationInfoInt(choice.GenerationInfo, "CompletionTokens")
+		run.usage.TotalTokens += generationInfoInt(choice.GenerationInfo, "TotalTokens")
+	}
+}
+
+func generationInfoInt(info map[string]any, key string) int {
+	switch v := info[key].(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// SavedGeneration returns the generation with id from the history table.
+func (b *bloggerImpl) SavedGeneration(ctx context.Context, id int64) (*Generation, error) {
+	return b.s.Generation(ctx, id)
+}
+
+// SavedGenerations returns up to limit generations from the history table,
+// newest first.
+func (b *bloggerImpl) SavedGenerations(ctx context.Context, limit int) ([]Generation, error) {
+	return b.s.Generations(ctx, limit)
+}
+
+// generationColumns are the columns of the history table, other than id.
+const generationColumns = `user_prompt, topic, format, requested_length, length, runner, model, prompt_set, style_profile,
+documents, store_version, context, content, prompt_tokens, completion_tokens, total_tokens, retrieval_ms, duration_ms, created_at`
+
+// scanGeneration scans a row of id followed by generationColumns.
+func scanGeneration(row interface{ Scan(dest ...any) error }) (*Generation, error) {
+	gen := &Generation{}
+	var documents []byte
+	var retrievalMs, durationMs int64
+	err := row.Scan(&gen.ID, &gen.UserPrompt, &gen.Topic, &gen.Format, &gen.RequestedLength, &gen.Length, &gen.Runner, &gen.Model,
+		&gen.PromptSet, &gen.StyleProfile, &documents, &gen.StoreVersion, &gen.Context, &gen.Content,
+		&gen.Usage.PromptTokens, &gen.Usage.CompletionTokens, &gen.Usage.TotalTokens, &retrievalMs, &durationMs, &gen.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) > 0 {
+		if err = json.Unmarshal(documents, &gen.Documents); err != nil {
+			return nil, err
+		}
+	}
+	gen.RetrievalDuration = time.Duration(retrievalMs) * time.Millisecond
+	gen.Duration = time.Duration(durationMs) * time.Millisecond
+	return gen, nil
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/ollama"
 	"github.com/tmc/langchaingo/llms/openai"
+	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/textsplitter"
 	lgdolt "github.com/tmc/langchaingo/vectorstores/dolt"
 	lgmd "github.com/tmc/langchaingo/vectorstores/mariadb"
@@ -28,16 +29,33 @@ import (
 type DocSourceType string
 
 type bloggerImpl struct {
-	llm                             llms.Model
-	s                               HasableVectorStore
-	splitter                        textsplitter.TextSplitter
-	includeFileFunc                 func(path string) bool
-	runner                          Runner
-	model                           Model
-	logger                          *zap.Logger
-	preContentSystemPrompt          string
-	postContentSystemPromptTemplate string
-	refineContextSystemPrompt       string
+	llm             llms.Model
+	embedder        embeddings.Embedder
+	s               HasableVectorStore
+	splitter        textsplitter.TextSplitter
+	chunkSettings   SplitterSettings
+	includeFileFunc func(path string) bool
+	runner          Runner
+	model           Model
+	storeType       StoreType
+	storeName       string
+	branch          string
+	logger          *zap.Logger
+	prompts         *PromptSet
+	styleProfile    string
+	mergeMessages   bool
+	factCheck       bool
+	factCheckRevise bool
+	longForm        bool
+	enforceLength   bool
+	lengthTolerance float64
+	saveHistory     bool
+	batchSize       int
+	batchTokens     int
+	asOf            string
+	tag             string
+	bootstrap       *BootstrapReport
+	dimensions      *dimensionsCheck
 }
 
 var _ Blogger = &bloggerImpl{}
@@ -57,98 +75,199 @@ func NewBlogger(
 		if err != nil {
 			return nil, err
 		}
-		llmClient, ok := llm.(embeddings.EmbedderClient)
-		if !ok {
-			return nil, fmt.Errorf("llm does not implement embeddings.EmbedderClient")
-		}
-		e, err = embeddings.NewEmbedder(llmClient)
-		if err != nil {
-			return nil, err
-		}
 	case OpenAIRunner:
 		llm, err = openai.New(openai.WithModel(string(config.Model)))
 		if err != nil {
 			return nil, err
 		}
-		llmClient, ok := llm.(embeddings.EmbedderClient)
-		if !ok {
-			return nil, fmt.Errorf("llm does not implement embeddings.EmbedderClient")
-		}
-		e, err = embeddings.NewEmbedder(llmClient)
-		if err != nil {
-			return nil, err
-		}
 	default:
 		return nil, fmt.Errorf("unsupported llm runner: %s", config.Runner)
 	}
+	llmClient, ok := llm.(embeddings.EmbedderClient)
+	if !ok {
+		return nil, fmt.Errorf("llm does not implement embeddings.EmbedderClient")
+	}
+	resilient := newResilientLLM(llm, llmClient, config, logger)
+	llm = resilient
+	var client embeddings.EmbedderClient = resilient
+	if config.EmbeddingCache != nil {
+		client = config.EmbeddingCache.client(resilient, config.Runner, config.Model, logger)
+	}
+	e, err = embeddings.NewEmbedder(client)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.Branch != "" && config.StoreType != Dolt {
+		return nil, fmt.Errorf("branches are only supported by dolt stores, not %s", config.StoreType)
+	}
+
+	var bootstrap *BootstrapReport
+	if config.Bootstrap {
+		bootstrap, err = bootstrapStore(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var vectorDimensions int
+	var probed bool
 	var s HasableVectorStore
 	switch config.StoreType {
 	case Postgres:
-		url := GetPostgresConnectionString(config.User, config.Password, config.Host, config.StoreName, config.Port)
-		vs, err := pgvector.New(
-			ctx,
-			pgvector.WithConnectionURL(url),
+		url, err := config.connectionString(config.StoreName)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := config.openPostgres(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		vectorDimensions, probed, err = openVectorDimensions(ctx, config.VectorDimensions, &PostgresHasableVectorStore{conn: pool}, e)
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+		opts := []pgvector.Option{
+			pgvector.WithConn(pool),
 			pgvector.WithEmbedder(e),
-		)
+			pgvector.WithVectorDimensions(vectorDimensions),
+		}
+		if config.Bootstrap {
+			// searches order by cosine distance, <=>.
+			opts = append(opts, pgvector.WithHNSWIndex(0, 0, "vector_cosine_ops"))
+		}
+		vs, err := pgvector.New(ctx, opts...)
 		if err != nil {
+			pool.Close()
 			return nil, err
 		}
 
-		s, err = NewPostgresHasableVectorStore(vs, url)
+		s, err = NewPostgresHasableVectorStore(vs, pool)
 		if err != nil {
+			pool.Close()
 			return nil, err
 		}
 	case Dolt:
-		url := GetDoltConnectionString(config.User, config.Password, config.Host, config.StoreName, config.Port)
+		databaseName := config.StoreName
+		if config.Branch != "" {
+			err = ensureDoltBranch(ctx, config, config.Branch)
+			if err != nil {
+				return nil, err
+			}
+			databaseName = fmt.Sprintf("%s/%s", config.StoreName, config.Branch)
+		}
+		url, err := config.connectionString(databaseName)
+		if err != nil {
+			return nil, err
+		}
+		db, err := config.openMySQL(url)
+		if err != nil {
+			return nil, err
+		}
 		vs, err := lgdolt.New(ctx,
-			lgdolt.WithConnectionURL(url),
+			lgdolt.WithDB(db),
 			lgdolt.WithEmbedder(e),
 			lgdolt.WithCreateEmbeddingIndexAfterAddDocuments(true))
 		if err != nil {
+			db.Close()
 			return nil, err
 		}
 
-		s, err = NewDoltHasableVectorStore(vs, url)
+		s, err = NewDoltHasableVectorStore(vs, db)
 		if err != nil {
+			db.Close()
 			return nil, err
 		}
+		// Dolt vectors are not sized, so its dimensions are only needed
+		// once they are checked.
+		vectorDimensions = config.VectorDimensions
 	case MariaDB:
-		url := GetMariaDBConnectionString(config.User, config.Password, config.Host, config.StoreName, config.Port)
+		url, err := config.connectionString(config.StoreName)
+		if err != nil {
+			return nil, err
+		}
+		db, err := config.openMySQL(url)
+		if err != nil {
+			return nil, err
+		}
+		vectorDimensions, probed, err = openVectorDimensions(ctx, config.VectorDimensions, &MariaDBHasableVectorStore{db: db}, e)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
 		vs, err := lgmd.New(ctx,
-			lgmd.WithConnectionURL(url),
+			lgmd.WithDB(db),
 			lgmd.WithEmbedder(e),
-			lgmd.WithVectorDimensions(config.VectorDimensions))
+			lgmd.WithVectorDimensions(vectorDimensions))
 		if err != nil {
+			db.Close()
 			return nil, err
 		}
 
-		s, err = NewMariaDBHasableVectorStore(vs, url)
+		s, err = NewMariaDBHasableVectorStore(vs, db)
 		if err != nil {
+			db.Close()
 			return nil, err
 		}
 	default:
 		return nil, fmt.Errorf("unsupported vector store: %s", config.StoreType)
 	}
 
+	if probed {
+		logger.Info("detected vector dimensions", zap.Int("dimensions", vectorDimensions))
+	}
+	// dimensions the store was opened with are checked against the
+	// embedder's once the blogger embeds or writes.
+	dimensions := &dimensionsCheck{allowChange: config.AllowVectorDimensionsChange}
+	if config.VectorDimensions != 0 || probed {
+		dimensions.dims, dimensions.probed = vectorDimensions, probed
+	}
+
+	prompts := config.PromptSet
+	if prompts == nil {
+		prompts, err = LoadPromptSet("", DefaultPromptSet)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &bloggerImpl{
-		s:                               s,
-		llm:                             llm,
-		splitter:                        config.Splitter,
-		includeFileFunc:                 config.IncludeFileFunc,
-		runner:                          config.Runner,
-		model:                           config.Model,
-		logger:                          logger,
-		preContentSystemPrompt:          config.PreContentSystemPrompt,
-		postContentSystemPromptTemplate: config.PostContentSystemPromptTemplate,
-		refineContextSystemPrompt:       config.RefineContextSystemPrompt,
+		s:               s,
+		llm:             llm,
+		embedder:        e,
+		splitter:        config.Splitter,
+		chunkSettings:   config.SplitterSettings,
+		includeFileFunc: config.IncludeFileFunc,
+		runner:          config.Runner,
+		model:           config.Model,
+		storeType:       config.StoreType,
+		storeName:       config.StoreName,
+		branch:          config.Branch,
+		logger:          logger,
+		prompts:         prompts,
+		styleProfile:    config.StyleProfile,
+		mergeMessages:   config.MergeMessages,
+		factCheck:       config.FactCheck,
+		factCheckRevise: config.FactCheckRevise,
+		longForm:        config.LongForm,
+		enforceLength:   config.EnforceLength,
+		lengthTolerance: config.LengthTolerance,
+		saveHistory:     config.SaveHistory,
+		batchSize:       config.EmbedBatchSize,
+		batchTokens:     config.EmbedBatchTokens,
+		asOf:            config.AsOf,
+		tag:             config.Tag,
+		bootstrap:       bootstrap,
+		dimensions:      dimensions,
 	}, nil
 }
 
 func (b *bloggerImpl) Store(ctx context.Context, docSourceType DocSourceType, dir string) error {
+	if err := b.checkDimensions(ctx); err != nil {
+		return err
+	}
+
 	files := make([]string, 0)
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, walkErr error) error {
 		if walkErr != nil {
@@ -169,6 +288,8 @@ func (b *bloggerImpl) Store(ctx context.Context, docSourceType DocSourceType, di
 
 	sort.Strings(files)
 
+	stored := make([]string, 0, len(files))
+	batch := &storeBatch{}
 	for _, file := range files {
 		content, err := os.ReadFile(file)
 		if err != nil {
@@ -188,30 +309,44 @@ func (b *bloggerImpl) Store(ctx context.Context, docSourceType DocSourceType, di
 			"md5":             contentHash,
 		}
 
-		has, err := b.s.Has(ctx, md)
-		if err != nil {
-			return err
+		has := batch.has(filepath.Base(file), contentHash)
+		if !has {
+			if has, err = b.s.Has(ctx, md); err != nil {
+				return err
+			}
 		}
 		if has {
 			b.logger.Info("document already exists", zap.String("doc_source_type", string(docSourceType)), zap.String("name", filepath.Base(file)))
 			continue
 		}
 
+		md["ingested_at"] = time.Now().UTC().Format(time.RFC3339)
 		docs, err := textsplitter.CreateDocuments(b.splitter, []string{string(content)}, []map[string]any{md})
 		if err != nil {
 			return err
 		}
 
-		start := time.Now()
-		_, err = b.s.AddDocuments(ctx, docs)
-		if err != nil {
-			return err
+		tokens := 0
+		for _, doc := range docs {
+			tokens += estimateTokens(doc.PageContent)
 		}
+		if b.batchFull(batch, docs, tokens) {
+			flushed, err := b.flushBatch(ctx, batch)
+			if err != nil {
+				return err
+			}
+			stored = append(stored, flushed...)
+		}
+		batch.add(filepath.Base(file), contentHash, docs, tokens)
+	}
 
-		b.logger.Info("finished storing document", zap.String("doc_source_type", string(docSourceType)), zap.String("name", filepath.Base(file)), zap.Duration("duration", time.Since(start)))
+	flushed, err := b.flushBatch(ctx, batch)
+	if err != nil {
+		return err
 	}
+	stored = append(stored, flushed...)
 
-	return nil
+	return b.commit(ctx, b.storeCommitMessage(docSourceType, stored))
 }
 
 func (b *bloggerImpl) getNumSearchDocs(length int) int {
@@ -230,97 +365,227 @@ func (b *bloggerImpl) getNumSearchDocs(length int) int {
 }
 
 func (b *bloggerImpl) refineContext(ctx context.Context, userPrompt, initialContext string) (string, error) {
-	promptSuffix := fmt.Sprintf(`Here is the user's prompt and retrieved context documents:
-
-# User Prompt
-
-`+"```"+`markdown
-%s
-`+"```"+`
+	prompt, err := b.prompts.Messages(RefineContextPromptTemplate, PromptData{
+		UserPrompt: userPrompt,
+		Context:    initialContext,
+	})
+	if err != nil {
+		return "", err
+	}
+	return b.complete(ctx, prompt, 0.3)
+}
 
-# Retrieved Context Documents
+// complete sends msgs to the llm and returns the full response.
+func (b *bloggerImpl) complete(ctx context.Context, msgs []llms.MessageContent, temperature float64) (string, error) {
+	return b.generateText(ctx, msgs, temperature, false)
+}
 
-%s
-`, userPrompt, initialContext)
+// stream is like complete, but also prints the response as it arrives.
+func (b *bloggerImpl) stream(ctx context.Context, msgs []llms.MessageContent, temperature float64) (string, error) {
+	return b.generateText(ctx, msgs, temperature, true)
+}
 
-	systemPrompt := b.refineContextSystemPrompt + "\n" + promptSuffix
-	msg := llms.MessageContent{
-		Role:  llms.ChatMessageTypeHuman,
-		Parts: []llms.ContentPart{llms.TextContent{Text: systemPrompt}},
+func (b *bloggerImpl) generateText(ctx context.Context, msgs []llms.MessageContent, temperature float64, print bool) (string, error) {
+	if b.mergeMessages {
+		msgs = mergeMessages(msgs)
 	}
 	var sb strings.Builder
-	_, err := b.llm.GenerateContent(ctx,
-		[]llms.MessageContent{msg},
-		llms.WithTemperature(0.3),
+	// a retried attempt streams the response again from its start.
+	ctx = withRetryHook(ctx, func() {
+		if print && sb.Len() > 0 {
+			fmt.Print("\n\n[retrying, discarding the text above]\n\n")
+		}
+		sb.Reset()
+	})
+	resp, err := b.llm.GenerateContent(ctx,
+		msgs,
+		llms.WithTemperature(temperature),
 		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			if print {
+				fmt.Print(string(chunk))
+			}
 			sb.WriteString(string(chunk))
 			return nil
 		}),
 	)
+	recordUsage(ctx, resp)
 	return sb.String(), err
 }
 
-func (b *bloggerImpl) Generate(ctx context.Context, userPrompt string, topic string, length int, outputFormat string) error {
+// formatContext wraps each document in a markdown code fence for use as prompt context.
+func formatContext(docs []schema.Document) string {
+	var contextOnly strings.Builder
+	for _, doc := range docs {
+		contextOnly.WriteString(fmt.Sprintf("\n```markdown\n%s\n```\n", doc.PageContent))
+	}
+	return contextOnly.String()
+}
+
+func (b *bloggerImpl) Generate(ctx context.Context, userPrompt string, topic string, length int, outputFormat string) (*Generation, error) {
+	format, err := GetOutputFormat(outputFormat)
+	if err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		length = format.DefaultLength
+	}
+
+	start := time.Now()
+	ctx, run := withGenerationRun(ctx)
+	if err := b.checkDimensions(ctx); err != nil {
+		return nil, err
+	}
+
+	storeVersion, err := b.storeVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := PromptData{
+		UserPrompt:   userPrompt,
+		Topic:        topic,
+		Length:       length,
+		OutputFormat: format.Prompt,
+	}
+	if b.styleProfile != "" {
+		profile, err := b.s.StyleProfile(ctx, b.styleProfile)
+		if err != nil {
+			return nil, err
+		}
+		req.Style = profile.Profile
+	}
+
+	var content string
+	if b.longForm {
+		content, req.Context, err = b.generateLongForm(ctx, req)
+	} else {
+		content, req.Context, err = b.generateSinglePass(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if b.enforceLength {
+		content, err = b.fitLength(ctx, content, length)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gen := &Generation{
+		Content:         content,
+		UserPrompt:      userPrompt,
+		Topic:           topic,
+		Context:         req.Context,
+		Format:          format.Name,
+		RequestedLength: length,
+		PromptSet:       b.prompts.String(),
+		StyleProfile:    b.styleProfile,
+	}
+
+	if b.factCheck {
+		gen.FactCheck, err = b.FactCheck(ctx, gen.Content)
+		if err != nil {
+			return nil, err
+		}
+		if b.factCheckRevise && gen.FactCheck.NumUnsupported() > 0 {
+			revised, err := b.reviseUnsupportedClaims(ctx, gen.Content, gen.FactCheck)
+			if err != nil {
+				return nil, err
+			}
+			gen.FactCheck.RevisedContent = revised
+			gen.Content = revised
+		}
+	}
+
+	gen.Length = countWords(gen.Content)
+	gen.Rendered = format.render(gen.Content)
+	gen.FormatProblems = format.validate(gen.Content)
+
+	gen.Runner = b.runner
+	gen.Model = b.model
+	gen.Documents = run.documents
+	gen.Usage = run.usage
+	gen.RetrievalDuration = run.retrieval
+	gen.StoreVersion = storeVersion
+	gen.Duration = time.Since(start)
+	gen.CreatedAt = time.Now().UTC()
+	if b.saveHistory {
+		if err = b.s.SaveGeneration(ctx, gen); err != nil {
+			return nil, err
+		}
+		if err = b.commit(ctx, fmt.Sprintf("Save generation %d: %s", gen.ID, topic)); err != nil {
+			return nil, err
+		}
+	}
+	return gen, nil
+}
+
+// generateSinglePass returns the generated content and the context it was
+// written from.
+func (b *bloggerImpl) generateSinglePass(ctx context.Context, req PromptData) (string, string, error) {
+	refinedContext, err := b.retrieveContext(ctx, req.UserPrompt, req.Length)
+	if err != nil {
+		return "", "", err
+	}
+
+	req.Context = refinedContext
+	msgs, err := b.prompts.Messages(GeneratePromptTemplate, req)
+	if err != nil {
+		return "", "", err
+	}
+
+	fmt.Println()
+	fmt.Println("FINAL PROMPT:")
+	for _, msg := range msgs {
+		fmt.Printf("[%s]\n", msg.Role)
+		for _, part := range msg.Parts {
+			fmt.Println(part)
+		}
+	}
+	fmt.Println()
+
+	content, err := b.stream(ctx, msgs, 0.3)
+	return content, refinedContext, err
+}
+
+// retrieveContext searches the store for documents relevant to query and asks
+// the llm to select and rerank them.
+func (b *bloggerImpl) retrieveContext(ctx context.Context, query string, length int) (string, error) {
 	numSearchDocs := b.getNumSearchDocs(length)
 
-	docs, err := b.s.SimilaritySearch(ctx, userPrompt, numSearchDocs)
+	start := time.Now()
+	docs, err := b.similaritySearch(ctx, query, numSearchDocs)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if len(docs) == 0 {
-		return errors.New("no relevant documents found")
+		return "", errors.New("no relevant documents found")
 	}
 
-	var contextOnly strings.Builder
 	for _, doc := range docs {
 		fmt.Println()
 		fmt.Println("DOC SIM SCORE: ", doc.Score)
 		fmt.Println()
-		contextOnly.WriteString(fmt.Sprintf("\n```markdown\n%s\n```\n", doc.PageContent))
 	}
 
-	initialContext := contextOnly.String()
+	initialContext := formatContext(docs)
 
 	fmt.Println()
 	fmt.Println("INITIAL CONTEXT: ", initialContext)
 	fmt.Println()
 
-	refinedContext, err := b.refineContext(ctx, userPrompt, initialContext)
+	refinedContext, err := b.refineContext(ctx, query, initialContext)
 	if err != nil {
-		return err
+		return "", err
 	}
+	recordDocuments(ctx, docs, time.Since(start))
 
 	fmt.Println()
 	fmt.Println("REFINED CONTEXT: ", refinedContext)
 	fmt.Println()
 
-	var sb strings.Builder
-	sb.WriteString(b.preContentSystemPrompt)
-	sb.WriteString("\n")
-	sb.WriteString(refinedContext)
-	sb.WriteString("\n")
-	sb.WriteString(fmt.Sprintf(b.postContentSystemPromptTemplate, topic, length, userPrompt, outputFormat))
-	systemPrompt := sb.String()
-
-	fmt.Println()
-	fmt.Println("FINAL SYSTEM PROMPT:")
-	fmt.Println(systemPrompt)
-	fmt.Println()
-
-	msg := llms.MessageContent{
-		Role:  llms.ChatMessageTypeHuman,
-		Parts: []llms.ContentPart{llms.TextContent{Text: systemPrompt}},
-	}
-
-	_, err = b.llm.GenerateContent(ctx,
-		[]llms.MessageContent{msg},
-		llms.WithTemperature(0.3),
-		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
-			fmt.Print(string(chunk))
-			return nil
-		}),
-	)
-	return err
+	return refinedContext, nil
 }
 
 func (b *bloggerImpl) contentMd5(data []byte) (string, error) {
@@ -336,24 +601,3 @@ func (b *bloggerImpl) contentMd5(data []byte) (string, error) {
 func (b *bloggerImpl) Close() error {
 	return b.s.Close()
 }
-
-func GetPostgresConnectionString(user, password, host, databaseName string, port int) string {
-	if password == "" {
-		return fmt.Sprintf("postgres://%s@%s:%d/%s", user, host, port, databaseName)
-	}
-	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s", user, password, host, port, databaseName)
-}
-
-func GetDoltConnectionString(user, password, host, databaseName string, port int) string {
-	if password == "" {
-		return fmt.Sprintf("%s@tcp(%s:%d)/%s?parseTime=true&multiStatements=true", user, host, port, databaseName)
-	}
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&multiStatements=true", user, password, host, port, databaseName)
-}
-
-func GetMariaDBConnectionString(user, password, host, databaseName string, port int) string {
-	if password == "" {
-		return fmt.Sprintf("tcp(%s:%d)/%s", host, port, databaseName)
-	}
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", user, password, host, port, databaseName)
-}
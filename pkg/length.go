@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultLengthTolerance = 0.1
+
+	maxContinuations = 3
+)
+
+func countWords(s string) int {
+	return len(strings.Fields(s))
+}
+
+// fitLength continues content that falls short of length and condenses
+// content that runs over it, within b.lengthTolerance.
+func (b *bloggerImpl) fitLength(ctx context.Context, content string, length int) (string, error) {
+	minLength := int(float64(length) * (1 - b.lengthTolerance))
+	maxLength := int(float64(length) * (1 + b.lengthTolerance))
+
+	for i := 0; i < maxContinuations; i++ {
+		words := countWords(content)
+		if words >= minLength {
+			break
+		}
+
+		fmt.Println()
+		fmt.Printf("CONTINUING CONTENT: %d of %d words\n", words, length)
+		fmt.Println()
+
+		continuation, err := b.continueContent(ctx, content, length-words)
+		if err != nil {
+			return "", err
+		}
+		if countWords(continuation) == 0 {
+			break
+		}
+		content = strings.TrimRight(content, "\n") + "\n\n" + strings.TrimSpace(continuation)
+	}
+
+	if words := countWords(content); words > maxLength {
+		fmt.Println()
+		fmt.Printf("CONDENSING CONTENT: %d of %d words\n", words, length)
+		fmt.Println()
+
+		return b.condenseContent(ctx, content, length)
+	}
+	return content, nil
+}
+
+func (b *bloggerImpl) continueContent(ctx context.Context, content string, remaining int) (string, error) {
+	prompt, err := b.prompts.Messages(ContinueContentPromptTemplate, PromptData{
+		Content:    content,
+		WordsToAdd: remaining,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return b.stream(ctx, prompt, 0.3)
+}
+
+func (b *bloggerImpl) condenseContent(ctx context.Context, content string, length int) (string, error) {
+	prompt, err := b.prompts.Messages(CondenseContentPromptTemplate, PromptData{
+		Content: content,
+		Length:  length,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return b.stream(ctx, prompt, 0.3)
+}
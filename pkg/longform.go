@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	minSectionLength = 150
+
+	// smoothed drafts shorter than this fraction of the stitched draft are
+	// discarded, since the model has summarized rather than edited.
+	minSmoothedLengthRatio = 0.8
+)
+
+type outlineSection struct {
+	Title string
+	Notes string
+}
+
+// generateLongForm writes an outline, then writes each section against its
+// own retrieved context, and finally smooths the transitions between sections.
+// It returns the content and the sections' contexts, each under its title.
+func (b *bloggerImpl) generateLongForm(ctx context.Context, req PromptData) (string, string, error) {
+	outline, err := b.generateOutline(ctx, req)
+	if err != nil {
+		return "", "", err
+	}
+	if len(outline) == 0 {
+		return "", "", errors.New("failed to generate an outline")
+	}
+
+	fmt.Println()
+	fmt.Println("OUTLINE:")
+	for i, section := range outline {
+		fmt.Printf("%d. %s\n", i+1, section.Title)
+	}
+	fmt.Println()
+
+	sectionLength := req.Length / len(outline)
+	if sectionLength < minSectionLength {
+		sectionLength = minSectionLength
+	}
+
+	sections := make([]string, 0, len(outline))
+	contexts := make([]string, 0, len(outline))
+	previous := ""
+	for i, section := range outline {
+		fmt.Println()
+		fmt.Printf("SECTION %d: %s\n", i+1, section.Title)
+		fmt.Println()
+
+		content, sectionContext, err := b.generateSection(ctx, req, outline, i, sectionLength, previous)
+		if err != nil {
+			return "", "", err
+		}
+		sections = append(sections, fmt.Sprintf("## %s\n\n%s", section.Title, strings.TrimSpace(content)))
+		contexts = append(contexts, fmt.Sprintf("## %s\n%s", section.Title, sectionContext))
+		previous = content
+	}
+
+	stitched := strings.Join(sections, "\n\n")
+
+	smoothed, err := b.smoothTransitions(ctx, stitched)
+	if err != nil {
+		return "", "", err
+	}
+	content := smoothed
+	if float64(countWords(smoothed)) < minSmoothedLengthRatio*float64(countWords(stitched)) {
+		b.logger.Info("discarding smoothed draft that dropped content")
+		content = stitched
+	}
+
+	// the smoothed draft is printed only once it is chosen, so what is
+	// printed is what is returned.
+	fmt.Println()
+	fmt.Println("FINAL CONTENT:")
+	fmt.Println()
+	fmt.Println(content)
+	return content, strings.Join(contexts, "\n"), nil
+}
+
+func (b *bloggerImpl) generateOutline(ctx context.Context, req PromptData) ([]outlineSection, error) {
+	req.NumSections = req.Length / 500
+	if req.NumSections < 3 {
+		req.NumSections = 3
+	}
+
+	prompt, err := b.prompts.Messages(OutlinePromptTemplate, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.complete(ctx, prompt, 0.3)
+	if err != nil {
+		return nil, err
+	}
+	return parseOutline(resp), nil
+}
+
+func (b *bloggerImpl) generateSection(
+	ctx context.Context,
+	req PromptData,
+	outline []outlineSection,
+	idx int,
+	length int,
+	previous string,
+) (string, string, error) {
+	section := outline[idx]
+
+	start := time.Now()
+	docs, err := b.similaritySearch(ctx, fmt.Sprintf("%s\n%s\n%s", req.Topic, section.Title, section.Notes), b.getNumSearchDocs(length))
+	if err != nil {
+		return "", "", err
+	}
+	recordDocuments(ctx, docs, time.Since(start))
+
+	titles := make([]string, 0, len(outline))
+	for _, s := range outline {
+		titles = append(titles, s.Title)
+	}
+
+	req.Length = length
+	req.Context = formatContext(docs)
+	req.Outline = titles
+	req.Section = section.Title
+	req.SectionNotes = section.Notes
+	req.PreviousSection = previous
+	prompt, err := b.prompts.Messages(SectionPromptTemplate, req)
+	if err != nil {
+		return "", "", err
+	}
+
+	content, err := b.stream(ctx, prompt, 0.3)
+	return content, req.Context, err
+}
+
+func (b *bloggerImpl) smoothTransitions(ctx context.Context, draft string) (string, error) {
+	prompt, err := b.prompts.Messages(SmoothTransitionsPromptTemplate, PromptData{Content: draft})
+	if err != nil {
+		return "", err
+	}
+	return b.complete(ctx, prompt, 0.3)
+}
+
+// parseOutline reads sections from an llm response where each section starts
+// with a "## " heading followed by optional notes.
+func parseOutline(resp string) []outlineSection {
+	sections := make([]outlineSection, 0)
+	var notes strings.Builder
+	for _, line := range strings.Split(resp, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "## ") {
+			if len(sections) > 0 {
+				sections[len(sections)-1].Notes = strings.TrimSpace(notes.String())
+			}
+			notes.Reset()
+			sections = append(sections, outlineSection{Title: strings.TrimSpace(strings.TrimPrefix(trimmed, "## "))})
+			continue
+		}
+		if len(sections) > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "```") {
+			notes.WriteString(trimmed)
+			notes.WriteString("\n")
+		}
+	}
+	if len(sections) > 0 {
+		sections[len(sections)-1].Notes = strings.TrimSpace(notes.String())
+	}
+	return sections
+}
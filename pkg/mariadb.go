@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
 
@@ -13,40 +12,95 @@ import (
 )
 
 type MariaDBHasableVectorStore struct {
-	db               *sql.DB
-	connectionString string
-	vs               vectorstores.VectorStore
+	db *sql.DB
+	vs vectorstores.VectorStore
 }
 
-func NewMariaDBHasableVectorStore(s vectorstores.VectorStore, connectionString string) (HasableVectorStore, error) {
-	db, err := sql.Open("mysql", connectionString)
-	if err != nil {
-		return nil, err
-	}
-	return &MariaDBHasableVectorStore{db: db, connectionString: connectionString, vs: s}, nil
+// NewMariaDBHasableVectorStore wraps s, using db to query the tables s writes. Close
+// closes db.
+func NewMariaDBHasableVectorStore(s vectorstores.VectorStore, db *sql.DB) (HasableVectorStore, error) {
+	return &MariaDBHasableVectorStore{db: db, vs: s}, nil
 }
 
 var _ HasableVectorStore = &MariaDBHasableVectorStore{}
 
 func (d *MariaDBHasableVectorStore) Has(ctx context.Context, metadata map[string]any) (bool, error) {
-	whereQuerys := make([]string, 0)
-	for k, v := range metadata {
-		whereQuerys = append(whereQuerys, fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(langchain_mariadb_embedding, '$.%s')) = '%s'", k, v))
-	}
-	whereQuery := strings.Join(whereQuerys, " AND ")
-	if len(whereQuery) == 0 {
-		whereQuery = "TRUE"
-	}
-
-	query := fmt.Sprintf("SELECT COUNT(*) FROM langchain_mariadb_embedding WHERE %s", whereQuery)
+	where, args := mysqlMetadataWhere("cmetadata", metadata)
+	query := fmt.Sprintf("SELECT COUNT(*) FROM langchain_mariadb_embedding WHERE %s", where)
 	var count int
-	err := d.db.QueryRowContext(ctx, query).Scan(&count)
+	err := d.db.QueryRowContext(ctx, query, args...).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
+func (d *MariaDBHasableVectorStore) Sample(ctx context.Context, metadata map[string]any, k int) ([]schema.Document, error) {
+	return mysqlSample(ctx, d.db, "langchain_mariadb_embedding", metadata, k)
+}
+
+func (d *MariaDBHasableVectorStore) Chunks(ctx context.Context, metadata map[string]any) ([]schema.Document, error) {
+	return mysqlChunks(ctx, d.db, "langchain_mariadb_embedding", metadata)
+}
+
+func (d *MariaDBHasableVectorStore) Documents(ctx context.Context, metadata map[string]any) ([]StoredDocument, error) {
+	return mysqlDocuments(ctx, d.db, "langchain_mariadb_embedding", metadata)
+}
+
+func (d *MariaDBHasableVectorStore) Delete(ctx context.Context, metadata map[string]any) (int, error) {
+	return mysqlDelete(ctx, d.db, "langchain_mariadb_embedding", metadata)
+}
+
+func (d *MariaDBHasableVectorStore) Embeddings(ctx context.Context, metadata map[string]any) ([]StoredChunk, error) {
+	return mysqlEmbeddings(ctx, d.db, "langchain_mariadb_embedding", "VEC_ToText(embedding)", metadata)
+}
+
+func (d *MariaDBHasableVectorStore) VectorDimensions(ctx context.Context) (int, error) {
+	return mysqlVectorDimensions(ctx, d.db, "langchain_mariadb_embedding", "VEC_ToText(embedding)")
+}
+
+func (d *MariaDBHasableVectorStore) CreateTables(ctx context.Context) error {
+	if err := mysqlCreateMetadataTable(ctx, d.db); err != nil {
+		return err
+	}
+	if err := mysqlCreateStyleProfilesTable(ctx, d.db); err != nil {
+		return err
+	}
+	return mysqlCreateGenerationsTable(ctx, d.db)
+}
+
+func (d *MariaDBHasableVectorStore) Metadata(ctx context.Context, name string) (string, bool, error) {
+	return mysqlMetadata(ctx, d.db, name)
+}
+
+func (d *MariaDBHasableVectorStore) SetMetadata(ctx context.Context, name string, value string) error {
+	return mysqlSetMetadata(ctx, d.db, name, value)
+}
+
+func (d *MariaDBHasableVectorStore) SaveStyleProfile(ctx context.Context, profile *StyleProfile) error {
+	return mysqlSaveStyleProfile(ctx, d.db, profile)
+}
+
+func (d *MariaDBHasableVectorStore) StyleProfile(ctx context.Context, name string) (*StyleProfile, error) {
+	return mysqlStyleProfile(ctx, d.db, name)
+}
+
+func (d *MariaDBHasableVectorStore) StyleProfiles(ctx context.Context) ([]StyleProfile, error) {
+	return mysqlStyleProfiles(ctx, d.db)
+}
+
+func (d *MariaDBHasableVectorStore) SaveGeneration(ctx context.Context, gen *Generation) error {
+	return mysqlSaveGeneration(ctx, d.db, gen)
+}
+
+func (d *MariaDBHasableVectorStore) Generation(ctx context.Context, id int64) (*Generation, error) {
+	return mysqlGeneration(ctx, d.db, id)
+}
+
+func (d *MariaDBHasableVectorStore) Generations(ctx context.Context, limit int) ([]Generation, error) {
+	return mysqlGenerations(ctx, d.db, limit)
+}
+
 func (d *MariaDBHasableVectorStore) AddDocuments(ctx context.Context, documents []schema.Document, opts ...vectorstores.Option) ([]string, error) {
 	return d.vs.AddDocuments(ctx, documents, opts...)
 }
@@ -0,0 +1,115 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"go.uber.org/zap"
+)
+
+const metadataTableName = "robot_blogger_metadata"
+
+// vectorDimensionsKey is the metadata key of the number of dimensions of the
+// store's vectors.
+const vectorDimensionsKey = "vector_dimensions"
+
+var ErrVectorDimensionsMismatch = errors.New("vector dimensions mismatch")
+
+// checkVectorDimensions compares dims, the dimensions of the vectors the
+// embedding model produces, to the dimensions recorded in the store's
+// metadata, and records dims if the store has none. Stores written before
+// the dimensions were recorded are checked against their stored vectors. If
+// allowChange is true, a mismatch replaces the recorded dimensions instead of
+// failing, for stores being migrated to a new model.
+func checkVectorDimensions(ctx context.Context, s HasableVectorStore, dims int, allowChange bool, logger *zap.Logger) error {
+	stored, ok, err := storedVectorDimensions(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if stored != 0 && stored != dims && !allowChange {
+		return fmt.Errorf("%w: the store holds %d dimensional vectors, but the model embeds %d dimensions, use another store or reembed it with this model", ErrVectorDimensionsMismatch, stored, dims)
+	}
+	if ok && stored == dims {
+		return nil
+	}
+	if stored != 0 && stored != dims {
+		logger.Info("changing vector dimensions", zap.Int("from", stored), zap.Int("to", dims))
+	}
+	return s.SetMetadata(ctx, vectorDimensionsKey, strconv.Itoa(dims))
+}
+
+// storedVectorDimensions returns the dimensions recorded in the store's
+// metadata, and whether they were recorded, else the dimensions of its stored
+// vectors, or 0 if it has none.
+func storedVectorDimensions(ctx context.Context, s HasableVectorStore) (int, bool, error) {
+	value, ok, err := s.Metadata(ctx, vectorDimensionsKey)
+	if err != nil {
+		return 0, false, err
+	}
+	if !ok {
+		dims, err := s.VectorDimensions(ctx)
+		return dims, false, err
+	}
+	dims, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid %s metadata %q: %w", vectorDimensionsKey, value, err)
+	}
+	return dims, true, nil
+}
+
+// openVectorDimensions returns the dimensions to open a store with: the
+// configured dimensions, else the store's own, else those e embeds into, in
+// which case probed is true. Only a new store costs an embedding call.
+func openVectorDimensions(ctx context.Context, configured int, s HasableVectorStore, e embeddings.Embedder) (dims int, probed bool, err error) {
+	if configured != 0 {
+		return configured, false, nil
+	}
+	if dims, _, err = storedVectorDimensions(ctx, s); err != nil || dims != 0 {
+		return dims, false, err
+	}
+	dims, err = probeVectorDimensions(ctx, e)
+	return dims, err == nil, err
+}
+
+// dimensionsCheck is the state of checking a blogger's vector dimensions
+// against its store, which is done once, before the blogger first embeds or
+// writes, so that commands that only read the store neither call the
+// embedder nor write its metadata.
+type dimensionsCheck struct {
+	mu sync.Mutex
+	// dims are the configured or probed dimensions, or 0 if the store was
+	// opened with its own and the embedder must be probed.
+	dims        int
+	probed      bool
+	allowChange bool
+	checked     bool
+}
+
+// checkDimensions checks the embedder's vector dimensions against the
+// store's, and records them if the store has none.
+func (b *bloggerImpl) checkDimensions(ctx context.Context) error {
+	c := b.dimensions
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checked {
+		return nil
+	}
+	if c.dims == 0 {
+		dims, err := probeVectorDimensions(ctx, b.embedder)
+		if err != nil {
+			return err
+		}
+		b.logger.Info("detected vector dimensions", zap.Int("dimensions", dims))
+		c.dims, c.probed = dims, true
+	}
+	if err := checkVectorDimensions(ctx, b.s, c.dims, c.allowChange, b.logger); err != nil {
+		return err
+	}
+	c.checked = true
+	return nil
+}
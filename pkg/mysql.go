@@ -0,0 +1,350 @@
+package pkg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// mysqlNoSuchTable is the error number of queries on a missing table.
+const mysqlNoSuchTable = 1146
+
+// The helpers in this file are shared by the stores that speak the MySQL
+// protocol, Dolt and MariaDB.
+
+// mysqlMetadataWhere returns a WHERE clause matching each key of metadata
+// against the JSON column, and its arguments.
+func mysqlMetadataWhere(column string, metadata map[string]any) (string, []any) {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	whereQuerys := make([]string, 0, len(keys))
+	args := make([]any, 0, len(keys)*2)
+	for _, k := range keys {
+		whereQuerys = append(whereQuerys, fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, ?)) = ?", column))
+		args = append(args, "$."+k, fmt.Sprint(metadata[k]))
+	}
+	if len(whereQuerys) == 0 {
+		return "TRUE", args
+	}
+	return strings.Join(whereQuerys, " AND "), args
+}
+
+func mysqlSample(ctx context.Context, db *sql.DB, table string, metadata map[string]any, k int) ([]schema.Document, error) {
+	where, args := mysqlMetadataWhere("cmetadata", metadata)
+	query := fmt.Sprintf("SELECT document, cmetadata FROM %s WHERE %s ORDER BY RAND() LIMIT ?", table, where)
+	return mysqlQueryDocuments(ctx, db, query, append(args, k)...)
+}
+
+func mysqlChunks(ctx context.Context, db *sql.DB, table string, metadata map[string]any) ([]schema.Document, error) {
+	where, args := mysqlMetadataWhere("cmetadata", metadata)
+	query := fmt.Sprintf("SELECT document, cmetadata FROM %s WHERE %s", table, where)
+	return mysqlQueryDocuments(ctx, db, query, args...)
+}
+
+// mysqlQueryDocuments runs a query selecting document and cmetadata columns.
+func mysqlQueryDocuments(ctx context.Context, db *sql.DB, query string, args ...any) ([]schema.Document, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := make([]schema.Document, 0)
+	for rows.Next() {
+		var content string
+		var metadata []byte
+		if err := rows.Scan(&content, &metadata); err != nil {
+			return nil, err
+		}
+		doc := schema.Document{PageContent: content}
+		if len(metadata) > 0 {
+			if err := json.Unmarshal(metadata, &doc.Metadata); err != nil {
+				return nil, err
+			}
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+func mysqlCreateStyleProfilesTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+name varchar(255) NOT NULL,
+doc_source_type varchar(255),
+runner varchar(64),
+model varchar(255),
+profile longtext,
+created_at datetime,
+PRIMARY KEY (name))`, styleProfilesTableName))
+	return err
+}
+
+func mysqlSaveStyleProfile(ctx context.Context, db *sql.DB, profile *StyleProfile) error {
+	if err := mysqlCreateStyleProfilesTable(ctx, db); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (name, doc_source_type, runner, model, profile, created_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE doc_source_type = VALUES(doc_source_type), runner = VALUES(runner), model = VALUES(model),
+profile = VALUES(profile), created_at = VALUES(created_at)`, styleProfilesTableName),
+		profile.Name, string(profile.DocSourceType), string(profile.Runner), string(profile.Model), profile.Profile, profile.CreatedAt)
+	return err
+}
+
+// mysqlStyleProfile and mysqlStyleProfiles do not create the style profiles
+// table, so that reading a store does not change it.
+func mysqlStyleProfile(ctx context.Context, db *sql.DB, name string) (*StyleProfile, error) {
+	query := fmt.Sprintf("SELECT name, doc_source_type, runner, model, profile, created_at FROM %s WHERE name = ?", styleProfilesTableName)
+	p := &StyleProfile{}
+	err := db.QueryRowContext(ctx, query, name).Scan(&p.Name, &p.DocSourceType, &p.Runner, &p.Model, &p.Profile, &p.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) || isMySQLNoSuchTable(err) {
+		return nil, fmt.Errorf("%w: %s", ErrStyleProfileNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func mysqlStyleProfiles(ctx context.Context, db *sql.DB) ([]StyleProfile, error) {
+	query := fmt.Sprintf("SELECT name, doc_source_type, runner, model, profile, created_at FROM %s ORDER BY name", styleProfilesTableName)
+	rows, err := db.QueryContext(ctx, query)
+	if isMySQLNoSuchTable(err) {
+		return []StyleProfile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := make([]StyleProfile, 0)
+	for rows.Next() {
+		var p StyleProfile
+		if err := rows.Scan(&p.Name, &p.DocSourceType, &p.Runner, &p.Model, &p.Profile, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+func mysqlCreateGenerationsTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+id bigint NOT NULL AUTO_INCREMENT,
+user_prompt longtext,
+topic text,
+format varchar(64),
+requested_length int,
+length int,
+runner varchar(64),
+model varchar(255),
+prompt_set varchar(255),
+style_profile varchar(255),
+documents json,
+store_version varchar(255),
+context longtext,
+content longtext,
+prompt_tokens int,
+completion_tokens int,
+total_tokens int,
+retrieval_ms bigint,
+duration_ms bigint,
+created_at datetime,
+PRIMARY KEY (id))`, generationsTableName))
+	return err
+}
+
+func mysqlSaveGeneration(ctx context.Context, db *sql.DB, gen *Generation) error {
+	if err := mysqlCreateGenerationsTable(ctx, db); err != nil {
+		return err
+	}
+	documents, err := json.Marshal(gen.Documents)
+	if err != nil {
+		return err
+	}
+	res, err := db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, generationsTableName, generationColumns),
+		gen.UserPrompt, gen.Topic, gen.Format, gen.RequestedLength, gen.Length, string(gen.Runner), string(gen.Model),
+		gen.PromptSet, gen.StyleProfile, string(documents), gen.StoreVersion, gen.Context, gen.Content,
+		gen.Usage.PromptTokens, gen.Usage.CompletionTokens, gen.Usage.TotalTokens,
+		gen.RetrievalDuration.Milliseconds(), gen.Duration.Milliseconds(), gen.CreatedAt)
+	if err != nil {
+		return err
+	}
+	gen.ID, err = res.LastInsertId()
+	return err
+}
+
+// mysqlGeneration and mysqlGenerations do not create the generations table,
+// so that reading a store does not change it.
+func mysqlGeneration(ctx context.Context, db *sql.DB, id int64) (*Generation, error) {
+	query := fmt.Sprintf("SELECT id, %s FROM %s WHERE id = ?", generationColumns, generationsTableName)
+	gen, err := scanGeneration(db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) || isMySQLNoSuchTable(err) {
+		return nil, fmt.Errorf("%w: %d", ErrGenerationNotFound, id)
+	}
+	return gen, err
+}
+
+func mysqlGenerations(ctx context.Context, db *sql.DB, limit int) ([]Generation, error) {
+	query := fmt.Sprintf("SELECT id, %s FROM %s ORDER BY id DESC LIMIT ?", generationColumns, generationsTableName)
+	rows, err := db.QueryContext(ctx, query, limit)
+	if isMySQLNoSuchTable(err) {
+		return []Generation{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gens := make([]Generation, 0)
+	for rows.Next() {
+		gen, err := scanGeneration(rows)
+		if err != nil {
+			return nil, err
+		}
+		gens = append(gens, *gen)
+	}
+	return gens, rows.Err()
+}
+
+func mysqlDocuments(ctx context.Context, db *sql.DB, table string, metadata map[string]any) ([]StoredDocument, error) {
+	where, args := mysqlMetadataWhere("cmetadata", metadata)
+	query := fmt.Sprintf(`SELECT name, doc_source_type, md5, runner, model, COUNT(*), SUM(CHAR_LENGTH(document)), MIN(ingested_at) FROM (
+    SELECT
+        COALESCE(JSON_UNQUOTE(JSON_EXTRACT(cmetadata, '$.name')), '') AS name,
+        COALESCE(JSON_UNQUOTE(JSON_EXTRACT(cmetadata, '$.doc_source_type')), '') AS doc_source_type,
+        COALESCE(JSON_UNQUOTE(JSON_EXTRACT(cmetadata, '$.md5')), '') AS md5,
+        COALESCE(JSON_UNQUOTE(JSON_EXTRACT(cmetadata, '$.runner')), '') AS runner,
+        COALESCE(JSON_UNQUOTE(JSON_EXTRACT(cmetadata, '$.model')), '') AS model,
+        COALESCE(JSON_UNQUOTE(JSON_EXTRACT(cmetadata, '$.ingested_at')), '') AS ingested_at,
+        document
+    FROM %s
+    WHERE %s
+) AS chunks
+GROUP BY name, doc_source_type, md5, runner, model
+ORDER BY doc_source_type, name, model`, table, where)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := make([]StoredDocument, 0)
+	for rows.Next() {
+		var doc StoredDocument
+		var ingestedAt string
+		if err := rows.Scan(&doc.Name, &doc.DocSourceType, &doc.MD5, &doc.Runner, &doc.Model, &doc.Chunks, &doc.Characters, &ingestedAt); err != nil {
+			return nil, err
+		}
+		doc.IngestedAt = parseIngestedAt(ingestedAt)
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+func mysqlDelete(ctx context.Context, db *sql.DB, table string, metadata map[string]any) (int, error) {
+	where, args := mysqlMetadataWhere("cmetadata", metadata)
+	res, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", table, where), args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// mysqlEmbeddings returns the chunks matching metadata with their vectors.
+// vector is the expression selecting the embedding column as a JSON array.
+func mysqlEmbeddings(ctx context.Context, db *sql.DB, table string, vector string, metadata map[string]any) ([]StoredChunk, error) {
+	where, args := mysqlMetadataWhere("cmetadata", metadata)
+	query := fmt.Sprintf("SELECT document, cmetadata, %s FROM %s WHERE %s", vector, table, where)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := make([]StoredChunk, 0)
+	for rows.Next() {
+		var chunk StoredChunk
+		var metadata, embedding []byte
+		if err := rows.Scan(&chunk.Content, &metadata, &embedding); err != nil {
+			return nil, err
+		}
+		if len(metadata) > 0 {
+			if err := json.Unmarshal(metadata, &chunk.Metadata); err != nil {
+				return nil, err
+			}
+		}
+		if err := json.Unmarshal(embedding, &chunk.Vector); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// mysqlVectorDimensions returns the number of dimensions of a stored vector,
+// or 0 if the table is empty. vector is as for mysqlEmbeddings.
+func mysqlVectorDimensions(ctx context.Context, db *sql.DB, table string, vector string) (int, error) {
+	var embedding []byte
+	err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT %s FROM %s LIMIT 1", vector, table)).Scan(&embedding)
+	if errors.Is(err, sql.ErrNoRows) || isMySQLNoSuchTable(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var v []float32
+	if err = json.Unmarshal(embedding, &v); err != nil {
+		return 0, err
+	}
+	return len(v), nil
+}
+
+func mysqlCreateMetadataTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+name varchar(255) NOT NULL,
+value text,
+PRIMARY KEY (name))`, metadataTableName))
+	return err
+}
+
+// mysqlMetadata reads name from the metadata table. It does not create the
+// table, so that reading a store does not change it.
+func mysqlMetadata(ctx context.Context, db *sql.DB, name string) (string, bool, error) {
+	var value string
+	err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT value FROM %s WHERE name = ?", metadataTableName), name).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) || isMySQLNoSuchTable(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func mysqlSetMetadata(ctx context.Context, db *sql.DB, name string, value string) error {
+	if err := mysqlCreateMetadataTable(ctx, db); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (name, value) VALUES (?, ?)
+ON DUPLICATE KEY UPDATE value = VALUES(value)`, metadataTableName), name, value)
+	return err
+}
+
+func isMySQLNoSuchTable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlNoSuchTable
+}
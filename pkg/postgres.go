@@ -2,48 +2,328 @@ package pkg
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/vectorstores"
 )
 
 type PostgresHasableVectorStore struct {
-	conn             *pgx.Conn
-	connectionString string
-	vs               vectorstores.VectorStore
+	conn *pgxpool.Pool
+	vs   vectorstores.VectorStore
 }
 
-func NewPostgresHasableVectorStore(s vectorstores.VectorStore, connectionString string) (*PostgresHasableVectorStore, error) {
-	conn, err := pgx.Connect(context.Background(), connectionString)
-	if err != nil {
-		return nil, err
-	}
-	return &PostgresHasableVectorStore{conn: conn, connectionString: connectionString, vs: s}, nil
+// NewPostgresHasableVectorStore wraps s, using pool to query the tables s
+// writes. Close closes pool.
+func NewPostgresHasableVectorStore(s vectorstores.VectorStore, pool *pgxpool.Pool) (*PostgresHasableVectorStore, error) {
+	return &PostgresHasableVectorStore{conn: pool, vs: s}, nil
 }
 
 var _ HasableVectorStore = &PostgresHasableVectorStore{}
 
 func (d *PostgresHasableVectorStore) Has(ctx context.Context, metadata map[string]any) (bool, error) {
-	whereQuerys := make([]string, 0)
-	for k, v := range metadata {
-		whereQuerys = append(whereQuerys, fmt.Sprintf("(langchain_pg_embedding.cmetadata ->> '%s') = '%s'", k, v))
-	}
-	whereQuery := strings.Join(whereQuerys, " AND ")
-	if len(whereQuery) == 0 {
-		whereQuery = "TRUE"
-	}
-	query := fmt.Sprintf("SELECT COUNT(*) FROM langchain_pg_embedding WHERE %s", whereQuery)
+	where, args := postgresMetadataWhere("cmetadata", metadata, 1)
+	query := fmt.Sprintf("SELECT COUNT(*) FROM langchain_pg_embedding WHERE %s", where)
 	var count int
-	err := d.conn.QueryRow(ctx, query).Scan(&count)
+	err := d.conn.QueryRow(ctx, query, args...).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
+func (d *PostgresHasableVectorStore) Sample(ctx context.Context, metadata map[string]any, k int) ([]schema.Document, error) {
+	where, args := postgresMetadataWhere("cmetadata", metadata, 1)
+	query := fmt.Sprintf("SELECT document, cmetadata FROM langchain_pg_embedding WHERE %s ORDER BY random() LIMIT $%d", where, len(args)+1)
+	return d.queryDocuments(ctx, query, append(args, k)...)
+}
+
+func (d *PostgresHasableVectorStore) Chunks(ctx context.Context, metadata map[string]any) ([]schema.Document, error) {
+	where, args := postgresMetadataWhere("cmetadata", metadata, 1)
+	query := fmt.Sprintf("SELECT document, cmetadata FROM langchain_pg_embedding WHERE %s", where)
+	return d.queryDocuments(ctx, query, args...)
+}
+
+// queryDocuments runs a query selecting document and cmetadata columns.
+func (d *PostgresHasableVectorStore) queryDocuments(ctx context.Context, query string, args ...any) ([]schema.Document, error) {
+	rows, err := d.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := make([]schema.Document, 0)
+	for rows.Next() {
+		var doc schema.Document
+		if err := rows.Scan(&doc.PageContent, &doc.Metadata); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+func (d *PostgresHasableVectorStore) Documents(ctx context.Context, metadata map[string]any) ([]StoredDocument, error) {
+	where, args := postgresMetadataWhere("cmetadata", metadata, 1)
+	query := fmt.Sprintf(`SELECT
+	COALESCE(cmetadata ->> 'name', '') AS name,
+	COALESCE(cmetadata ->> 'doc_source_type', '') AS doc_source_type,
+	COALESCE(cmetadata ->> 'md5', '') AS md5,
+	COALESCE(cmetadata ->> 'runner', '') AS runner,
+	COALESCE(cmetadata ->> 'model', '') AS model,
+	COUNT(*),
+	SUM(length(document)),
+	MIN(COALESCE(cmetadata ->> 'ingested_at', ''))
+	FROM langchain_pg_embedding
+	WHERE %s
+	GROUP BY 1, 2, 3, 4, 5
+	ORDER BY 2, 1, 5`, where)
+	rows, err := d.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := make([]StoredDocument, 0)
+	for rows.Next() {
+		var doc StoredDocument
+		var ingestedAt string
+		if err := rows.Scan(&doc.Name, &doc.DocSourceType, &doc.MD5, &doc.Runner, &doc.Model, &doc.Chunks, &doc.Characters, &ingestedAt); err != nil {
+			return nil, err
+		}
+		doc.IngestedAt = parseIngestedAt(ingestedAt)
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+func (d *PostgresHasableVectorStore) Delete(ctx context.Context, metadata map[string]any) (int, error) {
+	where, args := postgresMetadataWhere("cmetadata", metadata, 1)
+	tag, err := d.conn.Exec(ctx, fmt.Sprintf("DELETE FROM langchain_pg_embedding WHERE %s", where), args...)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (d *PostgresHasableVectorStore) Embeddings(ctx context.Context, metadata map[string]any) ([]StoredChunk, error) {
+	where, args := postgresMetadataWhere("cmetadata", metadata, 1)
+	query := fmt.Sprintf("SELECT document, cmetadata, embedding::text FROM langchain_pg_embedding WHERE %s", where)
+	rows, err := d.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := make([]StoredChunk, 0)
+	for rows.Next() {
+		var chunk StoredChunk
+		var embedding string
+		if err := rows.Scan(&chunk.Content, &chunk.Metadata, &embedding); err != nil {
+			return nil, err
+		}
+		// pgvector's text format is a JSON array.
+		if err := json.Unmarshal([]byte(embedding), &chunk.Vector); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+func (d *PostgresHasableVectorStore) VectorDimensions(ctx context.Context) (int, error) {
+	var dims int
+	err := d.conn.QueryRow(ctx, "SELECT vector_dims(embedding) FROM langchain_pg_embedding LIMIT 1").Scan(&dims)
+	if errors.Is(err, pgx.ErrNoRows) || isPostgresUndefinedTable(err) {
+		return 0, nil
+	}
+	return dims, err
+}
+
+func (d *PostgresHasableVectorStore) CreateTables(ctx context.Context) error {
+	if err := d.createMetadataTable(ctx); err != nil {
+		return err
+	}
+	if err := d.createStyleProfilesTable(ctx); err != nil {
+		return err
+	}
+	return d.createGenerationsTable(ctx)
+}
+
+func (d *PostgresHasableVectorStore) createMetadataTable(ctx context.Context) error {
+	_, err := d.conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name varchar NOT NULL,
+	value text,
+	PRIMARY KEY (name))`, metadataTableName))
+	return err
+}
+
+// Metadata reads name from the metadata table. It does not create the
+// table, so that reading a store does not change it.
+func (d *PostgresHasableVectorStore) Metadata(ctx context.Context, name string) (string, bool, error) {
+	var value string
+	err := d.conn.QueryRow(ctx, fmt.Sprintf("SELECT value FROM %s WHERE name = $1", metadataTableName), name).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) || isPostgresUndefinedTable(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (d *PostgresHasableVectorStore) SetMetadata(ctx context.Context, name string, value string) error {
+	if err := d.createMetadataTable(ctx); err != nil {
+		return err
+	}
+	_, err := d.conn.Exec(ctx, fmt.Sprintf(`INSERT INTO %s (name, value) VALUES ($1, $2)
+	ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value`, metadataTableName), name, value)
+	return err
+}
+
+func (d *PostgresHasableVectorStore) createStyleProfilesTable(ctx context.Context) error {
+	_, err := d.conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name varchar NOT NULL,
+	doc_source_type varchar,
+	runner varchar,
+	model varchar,
+	profile text,
+	created_at timestamptz,
+	PRIMARY KEY (name))`, styleProfilesTableName))
+	return err
+}
+
+func (d *PostgresHasableVectorStore) SaveStyleProfile(ctx context.Context, profile *StyleProfile) error {
+	if err := d.createStyleProfilesTable(ctx); err != nil {
+		return err
+	}
+	_, err := d.conn.Exec(ctx, fmt.Sprintf(`INSERT INTO %s (name, doc_source_type, runner, model, profile, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (name) DO UPDATE SET doc_source_type = EXCLUDED.doc_source_type, runner = EXCLUDED.runner,
+	model = EXCLUDED.model, profile = EXCLUDED.profile, created_at = EXCLUDED.created_at`, styleProfilesTableName),
+		profile.Name, string(profile.DocSourceType), string(profile.Runner), string(profile.Model), profile.Profile, profile.CreatedAt)
+	return err
+}
+
+// StyleProfile and StyleProfiles do not create the style profiles table, so
+// that reading a store does not change it.
+func (d *PostgresHasableVectorStore) StyleProfile(ctx context.Context, name string) (*StyleProfile, error) {
+	query := fmt.Sprintf("SELECT name, doc_source_type, runner, model, profile, created_at FROM %s WHERE name = $1", styleProfilesTableName)
+	p := &StyleProfile{}
+	err := d.conn.QueryRow(ctx, query, name).Scan(&p.Name, &p.DocSourceType, &p.Runner, &p.Model, &p.Profile, &p.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) || isPostgresUndefinedTable(err) {
+		return nil, fmt.Errorf("%w: %s", ErrStyleProfileNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (d *PostgresHasableVectorStore) StyleProfiles(ctx context.Context) ([]StyleProfile, error) {
+	query := fmt.Sprintf("SELECT name, doc_source_type, runner, model, profile, created_at FROM %s ORDER BY name", styleProfilesTableName)
+	rows, err := d.conn.Query(ctx, query)
+	if isPostgresUndefinedTable(err) {
+		return []StyleProfile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := make([]StyleProfile, 0)
+	for rows.Next() {
+		var p StyleProfile
+		if err := rows.Scan(&p.Name, &p.DocSourceType, &p.Runner, &p.Model, &p.Profile, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+func (d *PostgresHasableVectorStore) createGenerationsTable(ctx context.Context) error {
+	_, err := d.conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id bigserial NOT NULL,
+	user_prompt text,
+	topic text,
+	format varchar,
+	requested_length int,
+	length int,
+	runner varchar,
+	model varchar,
+	prompt_set varchar,
+	style_profile varchar,
+	documents jsonb,
+	store_version varchar,
+	context text,
+	content text,
+	prompt_tokens int,
+	completion_tokens int,
+	total_tokens int,
+	retrieval_ms bigint,
+	duration_ms bigint,
+	created_at timestamptz,
+	PRIMARY KEY (id))`, generationsTableName))
+	return err
+}
+
+func (d *PostgresHasableVectorStore) SaveGeneration(ctx context.Context, gen *Generation) error {
+	if err := d.createGenerationsTable(ctx); err != nil {
+		return err
+	}
+	documents, err := json.Marshal(gen.Documents)
+	if err != nil {
+		return err
+	}
+	return d.conn.QueryRow(ctx, fmt.Sprintf(`INSERT INTO %s (%s)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19) RETURNING id`, generationsTableName, generationColumns),
+		gen.UserPrompt, gen.Topic, gen.Format, gen.RequestedLength, gen.Length, string(gen.Runner), string(gen.Model),
+		gen.PromptSet, gen.StyleProfile, documents, gen.StoreVersion, gen.Context, gen.Content,
+		gen.Usage.PromptTokens, gen.Usage.CompletionTokens, gen.Usage.TotalTokens,
+		gen.RetrievalDuration.Milliseconds(), gen.Duration.Milliseconds(), gen.CreatedAt).Scan(&gen.ID)
+}
+
+// Generation and Generations do not create the generations table, so that
+// reading a store does not change it.
+func (d *PostgresHasableVectorStore) Generation(ctx context.Context, id int64) (*Generation, error) {
+	query := fmt.Sprintf("SELECT id, %s FROM %s WHERE id = $1", generationColumns, generationsTableName)
+	gen, err := scanGeneration(d.conn.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) || isPostgresUndefinedTable(err) {
+		return nil, fmt.Errorf("%w: %d", ErrGenerationNotFound, id)
+	}
+	return gen, err
+}
+
+func (d *PostgresHasableVectorStore) Generations(ctx context.Context, limit int) ([]Generation, error) {
+	query := fmt.Sprintf("SELECT id, %s FROM %s ORDER BY id DESC LIMIT $1", generationColumns, generationsTableName)
+	rows, err := d.conn.Query(ctx, query, limit)
+	if isPostgresUndefinedTable(err) {
+		return []Generation{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gens := make([]Generation, 0)
+	for rows.Next() {
+		gen, err := scanGeneration(rows)
+		if err != nil {
+			return nil, err
+		}
+		gens = append(gens, *gen)
+	}
+	return gens, rows.Err()
+}
+
 func (d *PostgresHasableVectorStore) AddDocuments(ctx context.Context, documents []schema.Document, opts ...vectorstores.Option) ([]string, error) {
 	return d.vs.AddDocuments(ctx, documents, opts...)
 }
@@ -53,5 +333,35 @@ func (d *PostgresHasableVectorStore) SimilaritySearch(ctx context.Context, query
 }
 
 func (d *PostgresHasableVectorStore) Close() error {
-	return d.conn.Close(context.Background())
+	d.conn.Close()
+	return nil
+}
+
+// postgresMetadataWhere returns a WHERE clause matching each key of metadata
+// against the JSON column, and its arguments. Placeholders are numbered from
+// firstArg.
+func postgresMetadataWhere(column string, metadata map[string]any, firstArg int) (string, []any) {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	whereQuerys := make([]string, 0, len(keys))
+	args := make([]any, 0, len(keys)*2)
+	for _, k := range keys {
+		whereQuerys = append(whereQuerys, fmt.Sprintf("(%s ->> $%d) = $%d", column, firstArg+len(args), firstArg+len(args)+1))
+		args = append(args, k, fmt.Sprint(metadata[k]))
+	}
+	if len(whereQuerys) == 0 {
+		return "TRUE", args
+	}
+	return strings.Join(whereQuerys, " AND "), args
+}
+
+// isPostgresUndefinedTable reports whether err is from a query on a missing
+// table.
+func isPostgresUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42P01"
 }
@@ -0,0 +1,243 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const DefaultPromptSet = "default"
+
+const (
+	GeneratePromptTemplate          = "generate.tmpl"
+	RefineContextPromptTemplate     = "refine_context.tmpl"
+	FactCheckClaimsPromptTemplate   = "fact_check_claims.tmpl"
+	FactCheckVerifyPromptTemplate   = "fact_check_verify.tmpl"
+	FactCheckRevisePromptTemplate   = "fact_check_revise.tmpl"
+	OutlinePromptTemplate           = "outline.tmpl"
+	SectionPromptTemplate           = "section.tmpl"
+	SmoothTransitionsPromptTemplate = "smooth_transitions.tmpl"
+	ContinueContentPromptTemplate   = "continue_content.tmpl"
+	CondenseContentPromptTemplate   = "condense_content.tmpl"
+	StyleProfilePromptTemplate      = "style_profile.tmpl"
+	RevisePromptTemplate            = "revise.tmpl"
+	ReviseSearchPromptTemplate      = "revise_search.tmpl"
+
+	promptTemplateExt    = ".tmpl"
+	promptSetVersionFile = "VERSION"
+)
+
+//go:embed prompts
+var builtinPrompts embed.FS
+
+// PromptData holds the variables available to prompt templates. Each template
+// uses only the fields relevant to it.
+type PromptData struct {
+	UserPrompt   string
+	Topic        string
+	Length       int
+	OutputFormat string
+	Context      string
+	Style        string
+
+	Content    string
+	Claim      string
+	Claims     []string
+	WordsToAdd int
+	Feedback   string
+
+	NumSections     int
+	Outline         []string
+	Section         string
+	SectionNotes    string
+	PreviousSection string
+}
+
+// PromptSet is a named, versioned collection of prompt templates.
+type PromptSet struct {
+	Name      string
+	Version   string
+	templates *template.Template
+}
+
+// LoadPromptSet loads the prompt set called name. If dir is empty, the set is
+// read from the prompts built into the binary, otherwise from dir/name.
+// Templates missing from a set are taken from the built-in default set.
+//
+// A set's version is read from its VERSION file, or derived from the contents
+// of the templates it resolves to, including those taken from the default set,
+// if it has none.
+func LoadPromptSet(dir string, name string) (*PromptSet, error) {
+	if name == "" {
+		name = DefaultPromptSet
+	}
+
+	defaults, err := fs.Sub(builtinPrompts, path.Join("prompts", DefaultPromptSet))
+	if err != nil {
+		return nil, err
+	}
+
+	var fsys fs.FS
+	if dir == "" {
+		fsys, err = fs.Sub(builtinPrompts, path.Join("prompts", name))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		fsys = os.DirFS(filepath.Join(dir, name))
+	}
+
+	files, err := fs.Glob(fsys, "*"+promptTemplateExt)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("prompt set %s has no %s files", name, promptTemplateExt)
+	}
+
+	tmpl := template.New(name).Funcs(template.FuncMap{
+		"inc":     func(i int) int { return i + 1 },
+		"message": messageMarker,
+	})
+	sources := make(map[string][]byte)
+	if name != DefaultPromptSet || dir != "" {
+		if err = parsePromptTemplates(tmpl, defaults, sources); err != nil {
+			return nil, err
+		}
+	}
+	if err = parsePromptTemplates(tmpl, fsys, sources); err != nil {
+		return nil, err
+	}
+
+	version, err := promptSetVersion(fsys, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PromptSet{Name: name, Version: version, templates: tmpl}, nil
+}
+
+// parsePromptTemplates parses the templates in fsys into tmpl, replacing any of
+// the same name, and records their sources in sources.
+func parsePromptTemplates(tmpl *template.Template, fsys fs.FS, sources map[string][]byte) error {
+	files, err := fs.Glob(fsys, "*"+promptTemplateExt)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		if _, err = tmpl.New(file).Parse(string(data)); err != nil {
+			return err
+		}
+		sources[file] = data
+	}
+	return nil
+}
+
+func promptSetVersion(fsys fs.FS, sources map[string][]byte) (string, error) {
+	data, err := fs.ReadFile(fsys, promptSetVersionFile)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	files := make([]string, 0, len(sources))
+	for file := range sources {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	hash := sha256.New()
+	for _, file := range files {
+		hash.Write([]byte(file))
+		hash.Write(sources[file])
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))[:12], nil
+}
+
+// String returns the set's name and version, ie default@1.
+func (p *PromptSet) String() string {
+	return fmt.Sprintf("%s@%s", p.Name, p.Version)
+}
+
+// Messages renders the template called name with data and splits the result
+// into chat messages. Templates start each message with {{ message "<role>" }},
+// where role is one of system, context, human or ai. Text before the first
+// marker, or in templates without markers, is sent as a human message.
+func (p *PromptSet) Messages(name string, data PromptData) ([]llms.MessageContent, error) {
+	var sb strings.Builder
+	if err := p.templates.ExecuteTemplate(&sb, name, data); err != nil {
+		return nil, err
+	}
+
+	rendered := sb.String()
+	matches := messageMarkerRegexp.FindAllStringSubmatchIndex(rendered, -1)
+
+	msgs := make([]llms.MessageContent, 0, len(matches)+1)
+	appendMessage := func(role string, text string) {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return
+		}
+		msgs = append(msgs, llms.TextParts(messageRoles[role], text))
+	}
+
+	start := 0
+	role := "human"
+	for _, m := range matches {
+		appendMessage(role, rendered[start:m[0]])
+		role = rendered[m[2]:m[3]]
+		start = m[1]
+	}
+	appendMessage(role, rendered[start:])
+	return msgs, nil
+}
+
+// messageRoles maps the roles templates may use to chat message types. Context
+// is sent as a human message so retrieved documents are never given the
+// authority of system instructions.
+var messageRoles = map[string]llms.ChatMessageType{
+	"system":  llms.ChatMessageTypeSystem,
+	"context": llms.ChatMessageTypeHuman,
+	"human":   llms.ChatMessageTypeHuman,
+	"ai":      llms.ChatMessageTypeAI,
+}
+
+var messageMarkerRegexp = regexp.MustCompile("\x00message:(\\w+)\x00")
+
+func messageMarker(role string) (string, error) {
+	if _, ok := messageRoles[role]; !ok {
+		return "", fmt.Errorf("unsupported message role: %s", role)
+	}
+	return "\x00message:" + role + "\x00", nil
+}
+
+// mergeMessages joins msgs into a single human message, for models that do not
+// support system messages.
+func mergeMessages(msgs []llms.MessageContent) []llms.MessageContent {
+	texts := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		for _, part := range msg.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				texts = append(texts, text.Text)
+			}
+		}
+	}
+	return []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, strings.Join(texts, "\n\n"))}
+}
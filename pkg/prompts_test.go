@@ -0,0 +1,136 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func writePromptSet(t *testing.T, dir string, name string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for file, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name, file), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadPromptSet(t *testing.T) {
+	defaults, err := LoadPromptSet("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defaults.Name != DefaultPromptSet || defaults.Version == "" {
+		t.Errorf("expected the default set with a version, got %s", defaults)
+	}
+
+	dir := t.TempDir()
+	writePromptSet(t, dir, "custom", map[string]string{GeneratePromptTemplate: "write about {{ .Topic }}"})
+	custom, err := LoadPromptSet(dir, "custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, err := custom.Messages(GeneratePromptTemplate, PromptData{Topic: "dolt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "write about dolt")}
+	if !reflect.DeepEqual(msgs, expected) {
+		t.Errorf("expected the set's own template, got %v", msgs)
+	}
+
+	// templates missing from the set come from the default set.
+	fallback, err := custom.Messages(OutlinePromptTemplate, PromptData{Topic: "dolt", NumSections: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin, err := defaults.Messages(OutlinePromptTemplate, PromptData{Topic: "dolt", NumSections: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fallback, builtin) {
+		t.Error("expected a missing template to come from the default set")
+	}
+
+	if _, err = LoadPromptSet(dir, "missing"); err == nil {
+		t.Error("expected an error for a set with no templates")
+	}
+	if _, err = LoadPromptSet("", "missing"); err == nil {
+		t.Error("expected an error for a built-in set that does not exist")
+	}
+}
+
+func TestPromptSetVersion(t *testing.T) {
+	outline, err := builtinPrompts.ReadFile("prompts/default/" + OutlinePromptTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writePromptSet(t, dir, "a", map[string]string{GeneratePromptTemplate: "write"})
+	// b's outline is the default one, so a and b resolve to the same templates.
+	writePromptSet(t, dir, "b", map[string]string{GeneratePromptTemplate: "write", OutlinePromptTemplate: string(outline)})
+	writePromptSet(t, dir, "c", map[string]string{GeneratePromptTemplate: "write more"})
+	writePromptSet(t, dir, "d", map[string]string{GeneratePromptTemplate: "write", promptSetVersionFile: " 2\n"})
+
+	versions := make(map[string]string)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		p, err := LoadPromptSet(dir, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions[name] = p.Version
+	}
+
+	if versions["a"] != versions["b"] {
+		t.Errorf("expected sets resolving to the same templates to share a version, got %s and %s", versions["a"], versions["b"])
+	}
+	if versions["a"] == versions["c"] {
+		t.Errorf("expected a changed template to change the version, got %s", versions["c"])
+	}
+	if versions["d"] != "2" {
+		t.Errorf("expected the VERSION file's version 2, got %s", versions["d"])
+	}
+}
+
+func TestPromptSetMessages(t *testing.T) {
+	dir := t.TempDir()
+	writePromptSet(t, dir, "roles", map[string]string{
+		"roles.tmpl": `preamble{{ message "system" }}be brief{{ message "context" }}{{ .Context }}{{ message "human" }}  {{ message "ai" }}ok`,
+		"bad.tmpl":   `{{ message "tool" }}`,
+	})
+	p, err := LoadPromptSet(dir, "roles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, err := p.Messages("roles.tmpl", PromptData{Context: "docs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "preamble"),
+		llms.TextParts(llms.ChatMessageTypeSystem, "be brief"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "docs"),
+		llms.TextParts(llms.ChatMessageTypeAI, "ok"),
+	}
+	if !reflect.DeepEqual(msgs, expected) {
+		t.Errorf("expected %v, got %v", expected, msgs)
+	}
+
+	merged := mergeMessages(msgs)
+	if !reflect.DeepEqual(merged, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "preamble\n\nbe brief\n\ndocs\n\nok")}) {
+		t.Errorf("expected a single merged human message, got %v", merged)
+	}
+
+	if _, err = p.Messages("bad.tmpl", PromptData{}); err == nil {
+		t.Error("expected an error for an unsupported role")
+	}
+}
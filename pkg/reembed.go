@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ReembedProgress reports the progress of a Reembed run after each document.
+type ReembedProgress struct {
+	Documents int
+	Done      int
+	Skipped   int
+	Chunks    int
+	Current   string
+	Elapsed   time.Duration
+}
+
+// Reembed copies the chunks of the stored documents matching filter into
+// target's store, embedding them with target's runner and model. Documents
+// target already has are skipped, so an interrupted run resumes where it
+// stopped. progress, if not nil, is called after each document.
+func (b *bloggerImpl) Reembed(ctx context.Context, target Blogger, filter DocumentFilter, progress func(ReembedProgress)) (*ReembedProgress, error) {
+	t, ok := target.(*bloggerImpl)
+	if !ok {
+		return nil, fmt.Errorf("unsupported reembed target: %T", target)
+	}
+	if t.storeType == b.storeType && t.storeName == b.storeName && t.branch == b.branch && t.runner == b.runner && t.model == b.model {
+		return nil, errors.New("reembed target must differ from the source")
+	}
+
+	// the target takes the target model's vector dimensions only while it
+	// has no documents, so vectors of two sizes never share its table.
+	targetDocs, err := t.s.Documents(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(targetDocs) == 0 {
+		t.dimensions.mu.Lock()
+		t.dimensions.allowChange = true
+		t.dimensions.mu.Unlock()
+	}
+	if err = t.checkDimensions(ctx); err != nil {
+		return nil, err
+	}
+
+	docs, err := b.s.Documents(ctx, filter.metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	p := ReembedProgress{Documents: len(docs)}
+	for _, doc := range docs {
+		p.Current = doc.Name
+		md := map[string]any{
+			"doc_source_type": string(doc.DocSourceType),
+			"name":            doc.Name,
+			"runner":          string(t.runner),
+			"model":           string(t.model),
+			"md5":             doc.MD5,
+		}
+
+		has, err := t.s.Has(ctx, md)
+		if err != nil {
+			return nil, err
+		}
+		if has {
+			p.Skipped++
+		} else {
+			chunks, err := b.s.Chunks(ctx, storedDocumentMetadata(doc))
+			if err != nil {
+				return nil, err
+			}
+			for i := range chunks {
+				if chunks[i].Metadata == nil {
+					chunks[i].Metadata = make(map[string]any)
+				}
+				chunks[i].Metadata["runner"] = string(t.runner)
+				chunks[i].Metadata["model"] = string(t.model)
+			}
+			if _, err = t.s.AddDocuments(ctx, chunks); err != nil {
+				return nil, fmt.Errorf("failed to reembed %s: %w", doc.Name, err)
+			}
+			p.Chunks += len(chunks)
+		}
+
+		p.Done++
+		p.Elapsed = time.Since(start)
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	b.logger.Info("reembedded documents", zap.Int("documents", p.Done), zap.Int("skipped", p.Skipped), zap.Int("chunks", p.Chunks), zap.Duration("duration", p.Elapsed))
+	if p.Done > p.Skipped {
+		message := fmt.Sprintf("Re-embed %d documents from %s/%s with %s/%s", p.Done-p.Skipped, b.runner, b.model, t.runner, t.model)
+		if err = t.commit(ctx, message); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
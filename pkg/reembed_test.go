@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func newTestReembedBloggers(target *memoryStore) (*bloggerImpl, *bloggerImpl) {
+	source := newMemoryStore()
+	source.chunks = []StoredChunk{
+		testChunk("a.md", OllamaRunner, "old", 1, 2),
+		testChunk("a.md", OllamaRunner, "old", 3, 4),
+		testChunk("b.md", OllamaRunner, "old", 5, 6),
+	}
+	source.metadata[vectorDimensionsKey] = "2"
+
+	b := &bloggerImpl{s: source, runner: OllamaRunner, model: "old", storeName: "source", dimensions: &dimensionsCheck{dims: 2}, logger: zap.NewNop()}
+	t := &bloggerImpl{s: target, runner: OllamaRunner, model: "new", storeName: "target", dimensions: &dimensionsCheck{dims: 3}, logger: zap.NewNop()}
+	return b, t
+}
+
+func TestReembed(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   DocumentFilter
+		existing []StoredChunk
+		done     int
+		skipped  int
+		chunks   int
+	}{
+		{name: "every document", done: 2, chunks: 3},
+		{name: "filtered documents", filter: DocumentFilter{Name: "b.md"}, done: 1, chunks: 1},
+		{
+			name:     "documents the target has",
+			existing: []StoredChunk{testChunk("a.md", OllamaRunner, "new", 1, 2, 3)},
+			done:     2,
+			skipped:  1,
+			chunks:   1,
+		},
+	}
+	for _, test := range tests {
+		target := newMemoryStore()
+		target.chunks = test.existing
+		b, tb := newTestReembedBloggers(target)
+
+		p, err := b.Reembed(context.Background(), tb, test.filter, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if p.Done != test.done || p.Skipped != test.skipped || p.Chunks != test.chunks {
+			t.Errorf("%s: expected %d done, %d skipped and %d chunks, got %+v", test.name, test.done, test.skipped, test.chunks, p)
+		}
+		for _, chunk := range target.chunks[len(test.existing):] {
+			if chunk.Metadata["runner"] != string(OllamaRunner) || chunk.Metadata["model"] != "new" {
+				t.Errorf("%s: expected chunks of ollama/new, got %v", test.name, chunk.Metadata)
+			}
+		}
+		if dims := target.metadata[vectorDimensionsKey]; dims != "3" {
+			t.Errorf("%s: expected the target to record 3 dimensions, got %q", test.name, dims)
+		}
+	}
+}
+
+func TestReembedTargetDimensions(t *testing.T) {
+	// an empty target takes the target model's dimensions.
+	target := newMemoryStore()
+	target.metadata[vectorDimensionsKey] = "2"
+	b, tb := newTestReembedBloggers(target)
+	if _, err := b.Reembed(context.Background(), tb, DocumentFilter{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if dims := target.metadata[vectorDimensionsKey]; dims != "3" {
+		t.Errorf("expected the empty target to record 3 dimensions, got %q", dims)
+	}
+
+	// a target with documents keeps its dimensions.
+	target = newMemoryStore()
+	target.chunks = []StoredChunk{testChunk("c.md", OllamaRunner, "other", 1, 2)}
+	target.metadata[vectorDimensionsKey] = "2"
+	b, tb = newTestReembedBloggers(target)
+	if _, err := b.Reembed(context.Background(), tb, DocumentFilter{}, nil); !errors.Is(err, ErrVectorDimensionsMismatch) {
+		t.Errorf("expected ErrVectorDimensionsMismatch, got %v", err)
+	}
+	if len(target.chunks) != 1 {
+		t.Errorf("expected the target to be unchanged, got %d chunks", len(target.chunks))
+	}
+}
+
+func TestReembedRejectsSource(t *testing.T) {
+	b, _ := newTestReembedBloggers(newMemoryStore())
+	if _, err := b.Reembed(context.Background(), b, DocumentFilter{}, nil); err == nil {
+		t.Error("expected an error reembedding a store into itself")
+	}
+}
@@ -0,0 +1,243 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"gitlab.com/golang-commonmark/markdown"
+)
+
+const codeHighlightStyle = "github"
+
+// newMarkdown returns a parser that escapes raw html in its input, so rendered
+// output only contains html produced by the renderer itself.
+func newMarkdown() *markdown.Markdown {
+	return markdown.New(
+		markdown.HTML(false),
+		markdown.Linkify(true),
+		markdown.Tables(true),
+		markdown.Nofollow(true),
+	)
+}
+
+// RenderHTML converts markdown content to sanitized html. Fenced code blocks
+// are highlighted with inline styles so they survive email clients that strip
+// stylesheets.
+func RenderHTML(content string) (string, error) {
+	md := newMarkdown()
+	tokens := md.Parse([]byte(content))
+
+	var buf bytes.Buffer
+	start := 0
+	for i, tok := range tokens {
+		fence, ok := tok.(*markdown.Fence)
+		if !ok {
+			continue
+		}
+		buf.WriteString(md.RenderTokensToString(tokens[start:i]))
+		if err := highlightCode(&buf, fence.Params, fence.Content); err != nil {
+			return "", err
+		}
+		start = i + 1
+	}
+	buf.WriteString(md.RenderTokensToString(tokens[start:]))
+	return buf.String(), nil
+}
+
+func highlightCode(w io.Writer, params, code string) error {
+	lang, _, _ := strings.Cut(strings.TrimSpace(params), " ")
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return err
+	}
+	return chromahtml.New().Format(w, styles.Get(codeHighlightStyle), iterator)
+}
+
+// RenderText converts markdown content to plain text, keeping list markers,
+// link targets and code blocks readable.
+func RenderText(content string) string {
+	tokens := newMarkdown().Parse([]byte(content))
+
+	var sb strings.Builder
+	listNumbers := make([]int, 0)
+	for _, tok := range tokens {
+		switch tok := tok.(type) {
+		case *markdown.HeadingClose, *markdown.TableClose:
+			sb.WriteString("\n\n")
+		case *markdown.ParagraphClose:
+			if tok.Hidden {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		case *markdown.BulletListClose, *markdown.OrderedListClose:
+			listNumbers = listNumbers[:len(listNumbers)-1]
+			if len(listNumbers) == 0 {
+				sb.WriteString("\n")
+			}
+		case *markdown.BulletListOpen:
+			listNumbers = append(listNumbers, 0)
+		case *markdown.OrderedListOpen:
+			listNumbers = append(listNumbers, tok.Order)
+		case *markdown.ListItemOpen:
+			indent := strings.Repeat("  ", len(listNumbers)-1)
+			if n := listNumbers[len(listNumbers)-1]; n > 0 {
+				sb.WriteString(fmt.Sprintf("%s%d. ", indent, n))
+				listNumbers[len(listNumbers)-1]++
+			} else {
+				sb.WriteString(indent + "- ")
+			}
+		case *markdown.Fence:
+			writeIndented(&sb, tok.Content)
+		case *markdown.CodeBlock:
+			writeIndented(&sb, tok.Content)
+		case *markdown.Hr:
+			sb.WriteString("---\n\n")
+		case *markdown.TrClose:
+			sb.WriteString("\n")
+		case *markdown.ThClose, *markdown.TdClose:
+			sb.WriteString("\t")
+		case *markdown.Inline:
+			renderInlineText(&sb, tok.Children)
+		}
+	}
+	return strings.TrimSpace(sb.String()) + "\n"
+}
+
+func renderInlineText(sb *strings.Builder, tokens []markdown.Token) {
+	href := ""
+	for _, tok := range tokens {
+		switch tok := tok.(type) {
+		case *markdown.Text:
+			sb.WriteString(tok.Content)
+		case *markdown.CodeInline:
+			sb.WriteString(tok.Content)
+		case *markdown.HTMLInline:
+			sb.WriteString(tok.Content)
+		case *markdown.Softbreak, *markdown.Hardbreak:
+			sb.WriteString("\n")
+		case *markdown.LinkOpen:
+			href = tok.Href
+		case *markdown.LinkClose:
+			if href != "" {
+				sb.WriteString(fmt.Sprintf(" (%s)", href))
+			}
+			href = ""
+		case *markdown.Image:
+			renderInlineText(sb, tok.Tokens)
+		}
+	}
+}
+
+func writeIndented(sb *strings.Builder, code string) {
+	for _, line := range strings.Split(strings.TrimRight(code, "\n"), "\n") {
+		sb.WriteString("    ")
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+}
+
+// Email is a message with plain text and html alternatives of the same body.
+type Email struct {
+	From    string
+	To      []string
+	Subject string
+	Date    time.Time
+	Text    string
+	HTML    string
+}
+
+// NewEmail renders markdown content as an Email. The subject is taken from a
+// leading "Subject:" line if there is one, then from a leading title heading,
+// and finally from defaultSubject.
+func NewEmail(content string, defaultSubject string) (*Email, error) {
+	content = strings.TrimSpace(content)
+	subject := defaultSubject
+	if s, ok := emailSubject(content); ok {
+		subject = s
+		_, content, _ = strings.Cut(content, "\n")
+	} else if line := firstLine(content); strings.HasPrefix(line, "# ") {
+		subject = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		_, content, _ = strings.Cut(content, "\n")
+	}
+
+	html, err := RenderHTML(content)
+	if err != nil {
+		return nil, err
+	}
+	return &Email{
+		Subject: subject,
+		Date:    time.Now(),
+		Text:    RenderText(content),
+		HTML:    html,
+	}, nil
+}
+
+// WriteTo writes e as an RFC 5322 message with a multipart/alternative body.
+func (e *Email) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	headers := []struct{ key, value string }{
+		{"From", e.From},
+		{"To", strings.Join(e.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", e.Subject)},
+		{"Date", e.Date.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary())},
+	}
+	for _, h := range headers {
+		if h.value == "" {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%s: %s\r\n", h.key, h.value))
+	}
+	buf.WriteString("\r\n")
+
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", e.Text},
+		{"text/html; charset=utf-8", e.HTML},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return 0, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(p.body)); err != nil {
+			return 0, err
+		}
+		if err := qw.Close(); err != nil {
+			return 0, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return 0, err
+	}
+
+	return buf.WriteTo(w)
+}
@@ -0,0 +1,281 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	DefaultMaxRetries              = 3
+	DefaultRetryBackoff            = time.Second
+	DefaultMaxRetryBackoff         = 30 * time.Second
+	DefaultCircuitBreakerThreshold = 5
+	DefaultCircuitBreakerCooldown  = 30 * time.Second
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker open, too many consecutive llm failures")
+
+// statusCodeRegexp matches the status code in the errors of the OpenAI
+// client, and at the start of those of the Ollama client, ie
+// "503 Service Unavailable".
+var statusCodeRegexp = regexp.MustCompile(`(?:status code: |^)(\d{3})\b`)
+
+// resilientLLM wraps the llm of a runner so that its generation and
+// embedding calls are rate limited, retried with exponential backoff and
+// jitter, and fail fast while a circuit breaker is open.
+type resilientLLM struct {
+	model    llms.Model
+	embedder embeddings.EmbedderClient
+	logger   *zap.Logger
+
+	maxRetries     int
+	backoff        time.Duration
+	maxBackoff     time.Duration
+	requests       *rate.Limiter
+	tokens         *rate.Limiter
+	breakerLimit   int
+	breakerTimeout time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+var _ llms.Model = &resilientLLM{}
+var _ embeddings.EmbedderClient = &resilientLLM{}
+
+func newResilientLLM(model llms.Model, embedder embeddings.EmbedderClient, config *Config, logger *zap.Logger) *resilientLLM {
+	r := &resilientLLM{
+		model:          model,
+		embedder:       embedder,
+		logger:         logger,
+		maxRetries:     config.MaxRetries,
+		backoff:        config.RetryBackoff,
+		maxBackoff:     config.MaxRetryBackoff,
+		breakerLimit:   config.CircuitBreakerThreshold,
+		breakerTimeout: config.CircuitBreakerCooldown,
+	}
+	if r.backoff <= 0 {
+		r.backoff = DefaultRetryBackoff
+	}
+	if r.maxBackoff < r.backoff {
+		r.maxBackoff = max(r.backoff, DefaultMaxRetryBackoff)
+	}
+	if config.RequestsPerMinute > 0 {
+		r.requests = rate.NewLimiter(rate.Limit(float64(config.RequestsPerMinute)/60), 1)
+	}
+	if config.TokensPerMinute > 0 {
+		r.tokens = rate.NewLimiter(rate.Limit(float64(config.TokensPerMinute)/60), config.TokensPerMinute)
+	}
+	return r
+}
+
+func (r *resilientLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	tokens := 0
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				tokens += estimateTokens(text.Text)
+			}
+		}
+	}
+
+	var resp *llms.ContentResponse
+	err := r.do(ctx, "generate", tokens, func(ctx context.Context) error {
+		var err error
+		resp, err = r.model.GenerateContent(ctx, messages, options...)
+		return err
+	})
+	return resp, err
+}
+
+func (r *resilientLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, r, prompt, options...)
+}
+
+func (r *resilientLLM) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	tokens := 0
+	for _, text := range texts {
+		tokens += estimateTokens(text)
+	}
+
+	var vectors [][]float32
+	err := r.do(ctx, "embed", tokens, func(ctx context.Context) error {
+		var err error
+		vectors, err = r.embedder.CreateEmbedding(ctx, texts)
+		return err
+	})
+	return vectors, err
+}
+
+// do calls fn, waiting for the rate limits before each attempt and retrying
+// it while it fails with a retryable error.
+func (r *resilientLLM) do(ctx context.Context, op string, tokens int, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		// a new call fails fast while the circuit breaker is open, but a
+		// retry waits for it to close, so an outage shorter than the
+		// cooldown does not fail a long Store.
+		if attempt == 0 {
+			if err := r.allow(); err != nil {
+				return err
+			}
+		} else if err := r.waitForBreaker(ctx); err != nil {
+			return err
+		}
+		if err := r.wait(ctx, tokens); err != nil {
+			return err
+		}
+
+		err := fn(ctx)
+		if err == nil {
+			r.record(nil)
+			return nil
+		}
+		// only failures a retry may fix count toward the circuit breaker,
+		// not bad requests or the caller canceling.
+		if !retryable(ctx, err) {
+			return err
+		}
+		r.record(err)
+		if attempt >= r.maxRetries {
+			return err
+		}
+
+		delay := r.delay(attempt)
+		r.logger.Info("retrying llm call", zap.String("op", op), zap.Int("attempt", attempt+1), zap.Duration("delay", delay), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if hook, ok := ctx.Value(retryHookKey{}).(func()); ok {
+			hook()
+		}
+	}
+}
+
+type retryHookKey struct{}
+
+// withRetryHook returns a context whose llm calls run hook before each retry,
+// ie to discard what a failed streaming attempt delivered.
+func withRetryHook(ctx context.Context, hook func()) context.Context {
+	return context.WithValue(ctx, retryHookKey{}, hook)
+}
+
+func (r *resilientLLM) wait(ctx context.Context, tokens int) error {
+	if r.requests != nil {
+		if err := r.requests.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if r.tokens != nil && tokens > 0 {
+		// a call larger than the whole budget waits for all of it.
+		if err := r.tokens.WaitN(ctx, min(tokens, r.tokens.Burst())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delay returns the backoff before retry attempt+1, a random duration
+// between half and all of backoff doubled attempt times, capped at
+// maxBackoff.
+func (r *resilientLLM) delay(attempt int) time.Duration {
+	ceiling := r.maxBackoff
+	if attempt < 32 {
+		ceiling = min(r.maxBackoff, r.backoff<<attempt)
+	}
+	return ceiling/2 + rand.N(ceiling/2+1)
+}
+
+// allow returns ErrCircuitOpen if the circuit breaker is open. Once its
+// cooldown passes, calls are let through again, and the first failure opens
+// it again.
+func (r *resilientLLM) allow() error {
+	if r.breakerLimit <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if wait := time.Until(r.openUntil); wait > 0 {
+		return fmt.Errorf("%w, retrying in %s", ErrCircuitOpen, wait.Round(time.Second))
+	}
+	return nil
+}
+
+// waitForBreaker waits until the circuit breaker, if open, lets calls through
+// again.
+func (r *resilientLLM) waitForBreaker(ctx context.Context) error {
+	r.mu.Lock()
+	wait := time.Until(r.openUntil)
+	r.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	r.logger.Info("waiting for circuit breaker", zap.Duration("wait", wait))
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+func (r *resilientLLM) record(err error) {
+	if r.breakerLimit <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.failures = 0
+		return
+	}
+	r.failures++
+	if r.failures >= r.breakerLimit {
+		r.openUntil = time.Now().Add(r.breakerTimeout)
+		r.failures = r.breakerLimit - 1
+		r.logger.Info("opened circuit breaker", zap.Duration("cooldown", r.breakerTimeout), zap.Error(err))
+	}
+}
+
+// retryable reports whether a failed call may succeed if retried: one that
+// failed with a 408, 429 or 5xx status, or with a network error or timeout.
+// Other errors, ie authentication, invalid requests and responses that
+// cannot be decoded, and canceled calls are not retried.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if m := statusCodeRegexp.FindStringSubmatch(err.Error()); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return code == 408 || code == 429 || code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// estimateTokens estimates the number of tokens of text, at about four
+// characters per token.
+func estimateTokens(text string) int {
+	return len(text)/4 + 1
+}
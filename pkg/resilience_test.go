@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/zap"
+)
+
+// streamingModel streams each of its responses in two chunks. The calls
+// listed in fail stream the first chunk and then fail.
+type streamingModel struct {
+	calls int
+	fail  map[int]error
+}
+
+func (m *streamingModel) GenerateContent(ctx context.Context, _ []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	call := m.calls
+	m.calls++
+
+	opts := llms.CallOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+	if err := opts.StreamingFunc(ctx, []byte("hello ")); err != nil {
+		return nil, err
+	}
+	if err := m.fail[call]; err != nil {
+		return nil, err
+	}
+	if err := opts.StreamingFunc(ctx, []byte("world")); err != nil {
+		return nil, err
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "hello world"}}}, nil
+}
+
+func (m *streamingModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+var connectionReset = &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+
+func newTestResilientLLM(model llms.Model) *resilientLLM {
+	config := NewConfig().WithRetryBackoff(time.Millisecond, time.Millisecond)
+	return newResilientLLM(model, nil, config, zap.NewNop())
+}
+
+func TestGenerateTextRetriesStreamFromStart(t *testing.T) {
+	model := &streamingModel{fail: map[int]error{0: connectionReset}}
+	b := &bloggerImpl{llm: newTestResilientLLM(model)}
+
+	content, err := b.complete(context.Background(), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.calls != 2 {
+		t.Errorf("expected 2 calls, got %d", model.calls)
+	}
+	if content != "hello world" {
+		t.Errorf("expected %q, got %q", "hello world", content)
+	}
+}
+
+func TestCircuitBreakerIgnoresNonRetryableErrors(t *testing.T) {
+	model := &streamingModel{fail: map[int]error{}}
+	for i := 0; i < DefaultCircuitBreakerThreshold+1; i++ {
+		model.fail[i] = errors.New("API returned unexpected status code: 400")
+	}
+	r := newTestResilientLLM(model)
+	b := &bloggerImpl{llm: r}
+
+	for i := 0; i < DefaultCircuitBreakerThreshold; i++ {
+		if _, err := b.complete(context.Background(), nil, 0); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.complete(ctx, nil, 0)
+
+	if err := r.allow(); err != nil {
+		t.Errorf("expected the circuit breaker to stay closed, got %v", err)
+	}
+	if model.calls != DefaultCircuitBreakerThreshold+1 {
+		t.Errorf("expected non-retryable errors not to be retried, got %d calls", model.calls)
+	}
+}
+
+func TestRetryWaitsForCircuitBreaker(t *testing.T) {
+	model := &streamingModel{fail: map[int]error{0: connectionReset}}
+	config := NewConfig().WithRetryBackoff(time.Millisecond, time.Millisecond).WithCircuitBreaker(1, 50*time.Millisecond)
+	r := newResilientLLM(model, nil, config, zap.NewNop())
+	b := &bloggerImpl{llm: r}
+
+	start := time.Now()
+	content, err := b.complete(context.Background(), nil, 0)
+	if err != nil {
+		t.Fatalf("expected the retry to wait for the circuit breaker, got %v", err)
+	}
+	if content != "hello world" {
+		t.Errorf("expected %q, got %q", "hello world", content)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the retry to wait out the cooldown, took %s", elapsed)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"openai rate limit", errors.New("API returned unexpected status code: 429: rate limited"), true},
+		{"openai server error", errors.New("API returned unexpected status code: 503"), true},
+		{"openai timeout", errors.New("API returned unexpected status code: 408"), true},
+		{"openai bad request", errors.New("API returned unexpected status code: 400: invalid"), false},
+		{"openai unauthorized", errors.New("API returned unexpected status code: 401"), false},
+		{"ollama unavailable", errors.New("503 Service Unavailable: loading model"), true},
+		{"ollama not found", errors.New("404 Not Found: model not found"), false},
+		{"connection reset", connectionReset, true},
+		{"wrapped connection refused", fmt.Errorf("embed: %w", syscall.ECONNREFUSED), true},
+		{"stream cut off", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"request timeout", fmt.Errorf("post: %w", context.DeadlineExceeded), true},
+		{"decode error", errors.New("invalid character '<' looking for beginning of value"), false},
+		{"unknown error", errors.New("something went wrong"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(context.Background(), tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if retryable(ctx, connectionReset) {
+		t.Error("expected a canceled call not to be retried")
+	}
+}
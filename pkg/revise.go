@@ -0,0 +1,218 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// reviseNumSearchDocs is the number of documents retrieved for each new topic
+// mentioned in feedback.
+const reviseNumSearchDocs = 4
+
+// Revision is an interactive editing session on a generated draft. It keeps
+// the retrieved context, every draft and the feedback conversation, so each
+// round of feedback edits the latest draft instead of regenerating it.
+type Revision struct {
+	b       *bloggerImpl
+	gen     Generation
+	format  *OutputFormat
+	req     PromptData
+	drafts  []string
+	history []llms.MessageContent
+}
+
+// Revise starts a Revision of gen. If gen has no retrieved context, ie it was
+// generated long-form or read from a file, context is retrieved for its user
+// prompt and topic.
+func (b *bloggerImpl) Revise(ctx context.Context, gen *Generation) (*Revision, error) {
+	format, err := GetOutputFormat(gen.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Revision{
+		b:      b,
+		gen:    *gen,
+		format: format,
+		drafts: []string{strings.TrimSpace(gen.Content)},
+		req: PromptData{
+			UserPrompt:   gen.UserPrompt,
+			Topic:        gen.Topic,
+			Length:       gen.RequestedLength,
+			OutputFormat: format.Prompt,
+			Context:      gen.Context,
+		},
+	}
+	r.gen.Format = format.Name
+	if r.req.Length == 0 {
+		r.req.Length = format.DefaultLength
+		r.gen.RequestedLength = format.DefaultLength
+	}
+
+	styleProfile := gen.StyleProfile
+	if styleProfile == "" {
+		styleProfile = b.styleProfile
+	}
+	if styleProfile != "" {
+		profile, err := b.s.StyleProfile(ctx, styleProfile)
+		if err != nil {
+			return nil, err
+		}
+		r.req.Style = profile.Profile
+		r.gen.StyleProfile = styleProfile
+	}
+
+	if r.req.Context == "" {
+		query := strings.TrimSpace(gen.UserPrompt + "\n" + gen.Topic)
+		if query == "" {
+			query = gen.Content
+		}
+		r.req.Context, err = b.retrieveContext(ctx, query, r.req.Length)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Draft returns the current draft.
+func (r *Revision) Draft() string {
+	return r.drafts[len(r.drafts)-1]
+}
+
+// Drafts returns every draft of the session, oldest first.
+func (r *Revision) Drafts() []string {
+	return r.drafts
+}
+
+// History returns the feedback and revised drafts exchanged so far.
+func (r *Revision) History() []llms.MessageContent {
+	return r.history
+}
+
+// Context returns the retrieved context the drafts are written from.
+func (r *Revision) Context() string {
+	return r.req.Context
+}
+
+// Generation returns the current draft as a Generation.
+func (r *Revision) Generation() *Generation {
+	gen := r.gen
+	gen.Content = r.Draft()
+	gen.Context = r.req.Context
+	if len(r.drafts) > 1 {
+		gen.FactCheck = nil
+	}
+	gen.Length = countWords(gen.Content)
+	gen.Rendered = r.format.render(gen.Content)
+	gen.FormatProblems = r.format.validate(gen.Content)
+	return &gen
+}
+
+// Revise applies feedback to the current draft and returns the revised draft.
+// If the feedback asks for information the draft does not have, context is
+// first retrieved for it.
+func (r *Revision) Revise(ctx context.Context, feedback string) (string, error) {
+	feedback = strings.TrimSpace(feedback)
+	if feedback == "" {
+		return r.Draft(), nil
+	}
+
+	query, err := r.searchQuery(ctx, feedback)
+	if err != nil {
+		return "", err
+	}
+	if query != "" {
+		if _, err = r.Search(ctx, query); err != nil {
+			return "", err
+		}
+	}
+
+	req := r.req
+	req.Content = r.drafts[0]
+	msgs, err := r.b.prompts.Messages(RevisePromptTemplate, req)
+	if err != nil {
+		return "", err
+	}
+	msgs = append(msgs, r.history...)
+	msgs = append(msgs, llms.TextParts(llms.ChatMessageTypeHuman, feedback))
+
+	draft, err := r.b.stream(ctx, msgs, 0.3)
+	if err != nil {
+		return "", err
+	}
+	draft = strings.TrimSpace(draft)
+
+	r.history = append(r.history,
+		llms.TextParts(llms.ChatMessageTypeHuman, feedback),
+		llms.TextParts(llms.ChatMessageTypeAI, draft),
+	)
+	r.drafts = append(r.drafts, draft)
+	return draft, nil
+}
+
+// Search retrieves documents for query and adds those not already in the
+// context. It returns the number of documents added.
+func (r *Revision) Search(ctx context.Context, query string) (int, error) {
+	docs, err := r.b.similaritySearch(ctx, query, reviseNumSearchDocs)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, doc := range docs {
+		if strings.Contains(r.req.Context, doc.PageContent) {
+			continue
+		}
+		r.req.Context += formatContext([]schema.Document{doc})
+		added++
+	}
+
+	fmt.Println()
+	fmt.Printf("RETRIEVED %d NEW DOCUMENTS FOR: %s\n", added, query)
+	fmt.Println()
+
+	return added, nil
+}
+
+// Undo discards the latest revision. It returns false if there is none.
+func (r *Revision) Undo() bool {
+	if len(r.drafts) == 1 {
+		return false
+	}
+	r.drafts = r.drafts[:len(r.drafts)-1]
+	r.history = r.history[:len(r.history)-2]
+	return true
+}
+
+// searchQuery asks the llm whether feedback needs new context, and returns a
+// search query for it, or an empty string if it does not.
+func (r *Revision) searchQuery(ctx context.Context, feedback string) (string, error) {
+	prompt, err := r.b.prompts.Messages(ReviseSearchPromptTemplate, PromptData{
+		Topic:    r.req.Topic,
+		Content:  r.Draft(),
+		Feedback: feedback,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.b.complete(ctx, prompt, 0)
+	if err != nil {
+		return "", err
+	}
+	return parseSearchQuery(resp), nil
+}
+
+func parseSearchQuery(resp string) string {
+	query := strings.Trim(firstLine(strings.TrimSpace(resp)), "\"'` ")
+	if strings.EqualFold(strings.TrimRight(query, "."), "none") {
+		return ""
+	}
+	return query
+}
@@ -0,0 +1,188 @@
+package pkg
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	snapshotVersion      = 1
+	snapshotManifestName = "manifest.json"
+	snapshotChunksName   = "chunks.jsonl"
+)
+
+// SplitterSettings describes how Store splits documents into chunks.
+type SplitterSettings struct {
+	Type         string `json:"type"`
+	ChunkSize    int    `json:"chunk_size"`
+	ChunkOverlap int    `json:"chunk_overlap"`
+}
+
+// SnapshotManifest describes the store a snapshot was taken of. It is the
+// first file of the archive, followed by the chunks as written by Export.
+type SnapshotManifest struct {
+	Version          int              `json:"version"`
+	CreatedAt        time.Time        `json:"created_at"`
+	StoreType        StoreType        `json:"store_type"`
+	StoreName        string           `json:"store_name"`
+	StoreVersion     string           `json:"store_version,omitempty"`
+	Runner           Runner           `json:"runner"`
+	Model            Model            `json:"model"`
+	VectorDimensions int              `json:"vector_dimensions"`
+	Splitter         SplitterSettings `json:"splitter"`
+	Documents        int              `json:"documents"`
+	Chunks           int              `json:"chunks"`
+}
+
+// Snapshot writes every stored chunk, with its metadata and vector, to w as
+// a zstd compressed tar archive, along with a manifest of the store.
+func (b *bloggerImpl) Snapshot(ctx context.Context, w io.Writer) (*SnapshotManifest, error) {
+	docs, err := b.s.Documents(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	dims, err := b.s.VectorDimensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	storeVersion, err := b.storeVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the chunks are exported to a temporary file first, since tar needs
+	// their size before writing them.
+	f, err := os.CreateTemp("", "robot-blogger-snapshot-*.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	n, err := b.Export(ctx, f, DocumentFilter{})
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	manifest := &SnapshotManifest{
+		Version:          snapshotVersion,
+		CreatedAt:        time.Now().UTC(),
+		StoreType:        b.storeType,
+		StoreName:        b.storeName,
+		StoreVersion:     storeVersion,
+		Runner:           b.runner,
+		Model:            b.model,
+		VectorDimensions: dims,
+		Splitter:         b.chunkSettings,
+		Documents:        len(docs),
+		Chunks:           n,
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	tw := tar.NewWriter(zw)
+	err = tw.WriteHeader(&tar.Header{Name: snapshotManifestName, Mode: 0644, Size: int64(len(manifestJSON)), ModTime: manifest.CreatedAt})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tw.Write(manifestJSON); err != nil {
+		return nil, err
+	}
+	err = tw.WriteHeader(&tar.Header{Name: snapshotChunksName, Mode: 0644, Size: info.Size(), ModTime: manifest.CreatedAt})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(tw, f); err != nil {
+		return nil, err
+	}
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Restore adds the chunks of a snapshot written by Snapshot to the store,
+// without embedding them again. The store must be empty, and use the runner
+// and model the snapshot was taken with. Dolt stores commit the restore.
+func (b *bloggerImpl) Restore(ctx context.Context, r io.Reader) (*SnapshotManifest, error) {
+	docs, err := b.s.Documents(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) > 0 {
+		return nil, fmt.Errorf("restore requires an empty store, but it has %d documents", len(docs))
+	}
+
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if hdr.Name != snapshotManifestName {
+		return nil, fmt.Errorf("invalid snapshot, expected %s, found %s", snapshotManifestName, hdr.Name)
+	}
+	manifest := &SnapshotManifest{}
+	if err = json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version > snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, upgrade robot-blogger", manifest.Version)
+	}
+	if manifest.Runner != b.runner || manifest.Model != b.model {
+		return nil, fmt.Errorf("snapshot was embedded with %s/%s, but the store uses %s/%s", manifest.Runner, manifest.Model, b.runner, b.model)
+	}
+
+	hdr, err = tr.Next()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid snapshot, missing %s", snapshotChunksName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Name != snapshotChunksName {
+		return nil, fmt.Errorf("invalid snapshot, expected %s, found %s", snapshotChunksName, hdr.Name)
+	}
+	n, err := b.importChunks(ctx, tr)
+	if err != nil {
+		return nil, err
+	}
+	if n != manifest.Chunks {
+		return nil, fmt.Errorf("restored %d chunks, but the snapshot has %d", n, manifest.Chunks)
+	}
+
+	message := fmt.Sprintf("Restore snapshot of %s taken %s", manifest.StoreName, manifest.CreatedAt.Format(time.RFC3339))
+	if manifest.StoreVersion != "" {
+		message += " at " + manifest.StoreVersion
+	}
+	return manifest, b.commit(ctx, message)
+}
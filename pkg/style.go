@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultStyleProfileSamples = 20
+
+	styleProfilesTableName = "robot_blogger_style_profiles"
+)
+
+var ErrStyleProfileNotFound = errors.New("style profile not found")
+
+// StyleProfile is a style guide distilled from stored documents, used to
+// describe the voice generated content should be written in.
+type StyleProfile struct {
+	Name          string
+	DocSourceType DocSourceType
+	Runner        Runner
+	Model         Model
+	Profile       string
+	CreatedAt     time.Time
+}
+
+// CreateStyleProfile samples stored documents of docSourceType, asks the llm
+// to describe their style, and saves the result as the style profile name.
+func (b *bloggerImpl) CreateStyleProfile(ctx context.Context, name string, docSourceType DocSourceType, numSamples int) (*StyleProfile, error) {
+	docs, err := b.s.Sample(ctx, map[string]any{"doc_source_type": string(docSourceType)}, numSamples)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no stored documents found with doc type %s", docSourceType)
+	}
+
+	b.logger.Info("creating style profile", zap.String("name", name), zap.Int("samples", len(docs)))
+
+	prompt, err := b.prompts.Messages(StyleProfilePromptTemplate, PromptData{Context: formatContext(docs)})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.complete(ctx, prompt, 0.3)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &StyleProfile{
+		Name:          name,
+		DocSourceType: docSourceType,
+		Runner:        b.runner,
+		Model:         b.model,
+		Profile:       strings.TrimSpace(resp),
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err = b.s.SaveStyleProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (b *bloggerImpl) StyleProfile(ctx context.Context, name string) (*StyleProfile, error) {
+	return b.s.StyleProfile(ctx, name)
+}
+
+func (b *bloggerImpl) StyleProfiles(ctx context.Context) ([]StyleProfile, error) {
+	return b.s.StyleProfiles(ctx)
+}
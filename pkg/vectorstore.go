@@ -3,11 +3,36 @@ package pkg
 import (
 	"context"
 
+	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/vectorstores"
 )
 
 type HasableVectorStore interface {
 	Has(ctx context.Context, metadata map[string]any) (bool, error)
+	Sample(ctx context.Context, metadata map[string]any, k int) ([]schema.Document, error)
+	// Chunks returns every stored chunk matching metadata.
+	Chunks(ctx context.Context, metadata map[string]any) ([]schema.Document, error)
+	Documents(ctx context.Context, metadata map[string]any) ([]StoredDocument, error)
+	// Delete deletes the chunks matching metadata and returns how many it deleted.
+	Delete(ctx context.Context, metadata map[string]any) (int, error)
+	// Embeddings returns every stored chunk matching metadata with its vector.
+	Embeddings(ctx context.Context, metadata map[string]any) ([]StoredChunk, error)
+	// VectorDimensions returns the number of dimensions of the stored vectors,
+	// or 0 if the store is empty.
+	VectorDimensions(ctx context.Context) (int, error)
+	// CreateTables creates the tables robot-blogger keeps beside the
+	// embeddings, which are otherwise created on first use.
+	CreateTables(ctx context.Context) error
+	// Metadata returns the value of the store metadata name, and whether it
+	// is set.
+	Metadata(ctx context.Context, name string) (string, bool, error)
+	SetMetadata(ctx context.Context, name string, value string) error
+	SaveStyleProfile(ctx context.Context, profile *StyleProfile) error
+	StyleProfile(ctx context.Context, name string) (*StyleProfile, error)
+	StyleProfiles(ctx context.Context) ([]StyleProfile, error)
+	SaveGeneration(ctx context.Context, gen *Generation) error
+	Generation(ctx context.Context, id int64) (*Generation, error)
+	Generations(ctx context.Context, limit int) ([]Generation, error)
 	Close() error
 	vectorstores.VectorStore
 }
@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// memoryStore is the part of a HasableVectorStore the tests use, kept in
+// memory. Its other methods panic.
+type memoryStore struct {
+	HasableVectorStore
+	chunks   []StoredChunk
+	metadata map[string]string
+}
+
+var _ HasableVectorStore = &memoryStore{}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{metadata: make(map[string]string)}
+}
+
+func matchesMetadata(chunk StoredChunk, md map[string]any) bool {
+	for k, v := range md {
+		if fmt.Sprint(chunk.Metadata[k]) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *memoryStore) AddDocuments(ctx context.Context, docs []schema.Document, options ...vectorstores.Option) ([]string, error) {
+	opts := vectorstores.Options{}
+	for _, option := range options {
+		option(&opts)
+	}
+	var vectors [][]float32
+	if opts.Embedder != nil {
+		texts := make([]string, len(docs))
+		for i, doc := range docs {
+			texts[i] = doc.PageContent
+		}
+		var err error
+		if vectors, err = opts.Embedder.EmbedDocuments(ctx, texts); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]string, 0, len(docs))
+	for i, doc := range docs {
+		chunk := StoredChunk{Content: doc.PageContent, Metadata: doc.Metadata}
+		if vectors != nil {
+			chunk.Vector = vectors[i]
+		}
+		s.chunks = append(s.chunks, chunk)
+		ids = append(ids, fmt.Sprint(len(s.chunks)))
+	}
+	return ids, nil
+}
+
+func (s *memoryStore) Has(_ context.Context, md map[string]any) (bool, error) {
+	for _, chunk := range s.chunks {
+		if matchesMetadata(chunk, md) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryStore) Chunks(_ context.Context, md map[string]any) ([]schema.Document, error) {
+	docs := make([]schema.Document, 0)
+	for _, chunk := range s.chunks {
+		if matchesMetadata(chunk, md) {
+			metadata := make(map[string]any, len(chunk.Metadata))
+			for k, v := range chunk.Metadata {
+				metadata[k] = v
+			}
+			docs = append(docs, schema.Document{PageContent: chunk.Content, Metadata: metadata})
+		}
+	}
+	return docs, nil
+}
+
+func (s *memoryStore) Documents(_ context.Context, md map[string]any) ([]StoredDocument, error) {
+	docs := make([]StoredDocument, 0)
+	seen := make(map[string]bool)
+	for _, chunk := range s.chunks {
+		doc := StoredDocument{
+			Name:          fmt.Sprint(chunk.Metadata["name"]),
+			DocSourceType: DocSourceType(fmt.Sprint(chunk.Metadata["doc_source_type"])),
+			MD5:           fmt.Sprint(chunk.Metadata["md5"]),
+			Runner:        Runner(fmt.Sprint(chunk.Metadata["runner"])),
+			Model:         Model(fmt.Sprint(chunk.Metadata["model"])),
+		}
+		key := fmt.Sprint(storedDocumentMetadata(doc))
+		if !matchesMetadata(chunk, md) || seen[key] {
+			continue
+		}
+		seen[key] = true
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (s *memoryStore) VectorDimensions(_ context.Context) (int, error) {
+	for _, chunk := range s.chunks {
+		if chunk.Vector != nil {
+			return len(chunk.Vector), nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *memoryStore) Metadata(_ context.Context, name string) (string, bool, error) {
+	value, ok := s.metadata[name]
+	return value, ok, nil
+}
+
+func (s *memoryStore) SetMetadata(_ context.Context, name string, value string) error {
+	s.metadata[name] = value
+	return nil
+}
+
+func (s *memoryStore) Embeddings(_ context.Context, md map[string]any) ([]StoredChunk, error) {
+	chunks := make([]StoredChunk, 0)
+	for _, chunk := range s.chunks {
+		if matchesMetadata(chunk, md) {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks, nil
+}
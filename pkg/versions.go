@@ -0,0 +1,318 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/schema"
+	"go.uber.org/zap"
+)
+
+var ErrVersioningUnsupported = errors.New("vector store does not support versioning")
+
+// StoreVersion is a commit of a versioned vector store.
+type StoreVersion struct {
+	Hash      string
+	Committer string
+	Date      time.Time
+	Message   string
+	Tags      []string
+}
+
+// StoreBranch is a branch of a versioned vector store.
+type StoreBranch struct {
+	Name    string
+	Hash    string
+	Date    time.Time
+	Message string
+}
+
+// ChunkCount is the number of stored chunks of a doc type embedded by a model.
+type ChunkCount struct {
+	DocSourceType string
+	Model         string
+	Count         int
+}
+
+// BranchDiff compares the chunks stored on two revisions of a store.
+type BranchDiff struct {
+	From       string
+	To         string
+	FromCounts []ChunkCount
+	ToCounts   []ChunkCount
+	// Added and Removed are the number of chunks added and removed going
+	// from From to To.
+	Added   int
+	Removed int
+}
+
+// VersionedVectorStore is implemented by vector stores that can commit their
+// contents and read prior commits, ie Dolt.
+type VersionedVectorStore interface {
+	// Commit commits all changes to the store and returns the new commit's
+	// hash, or an empty string if there was nothing to commit.
+	Commit(ctx context.Context, message string) (string, error)
+	Tag(ctx context.Context, name string, revision string) error
+	CreateBranch(ctx context.Context, name string, revision string) error
+	// ResolveRevision returns the commit hash of a branch, tag or commit.
+	ResolveRevision(ctx context.Context, revision string) (string, error)
+	Versions(ctx context.Context, limit int) ([]StoreVersion, error)
+	Branches(ctx context.Context) ([]StoreBranch, error)
+	// Merge merges branch into the store's branch and returns the resulting
+	// commit hash.
+	Merge(ctx context.Context, branch string) (string, error)
+	ChunkCounts(ctx context.Context, revision string) ([]ChunkCount, error)
+	// DiffChunks returns the number of chunks added and removed between two
+	// revisions.
+	DiffChunks(ctx context.Context, from string, to string) (int, int, error)
+	// SimilaritySearchAsOf is like SimilaritySearch, but searches the store's
+	// contents as of revision using an already embedded query.
+	SimilaritySearchAsOf(ctx context.Context, embedding []float32, k int, revision string) ([]schema.Document, error)
+}
+
+func (b *bloggerImpl) versionedStore() (VersionedVectorStore, error) {
+	vs, ok := b.s.(VersionedVectorStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrVersioningUnsupported, b.s)
+	}
+	return vs, nil
+}
+
+// similaritySearch searches the store, or its contents as of b.asOf if set.
+func (b *bloggerImpl) similaritySearch(ctx context.Context, query string, k int) ([]schema.Document, error) {
+	if b.asOf == "" {
+		return b.s.SimilaritySearch(ctx, query, k)
+	}
+	vs, err := b.versionedStore()
+	if err != nil {
+		return nil, err
+	}
+	embedding, err := b.embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return vs.SimilaritySearchAsOf(ctx, embedding, k, b.asOf)
+}
+
+// storeVersion returns the commit hash generations read the store at, or an
+// empty string if the store is not versioned.
+func (b *bloggerImpl) storeVersion(ctx context.Context) (string, error) {
+	vs, ok := b.s.(VersionedVectorStore)
+	if !ok {
+		return "", nil
+	}
+	revision := b.asOf
+	if revision == "" {
+		revision = "HEAD"
+	}
+	return vs.ResolveRevision(ctx, revision)
+}
+
+// commit commits the store, if it is versioned, and tags the commit with
+// b.tag if set. If there is nothing to commit, the tag is added to HEAD.
+func (b *bloggerImpl) commit(ctx context.Context, message string) error {
+	vs, ok := b.s.(VersionedVectorStore)
+	if !ok {
+		return nil
+	}
+	hash, err := vs.Commit(ctx, message)
+	if err != nil {
+		return err
+	}
+	if hash != "" {
+		b.logger.Info("committed store", zap.String("commit", hash), zap.String("message", firstLine(message)))
+	}
+	if b.tag == "" {
+		return nil
+	}
+	if hash == "" {
+		hash = "HEAD"
+	}
+	if err = vs.Tag(ctx, b.tag, hash); err != nil {
+		return err
+	}
+	b.logger.Info("tagged store", zap.String("tag", b.tag), zap.String("commit", hash))
+	return nil
+}
+
+// storeCommitMessage describes a Store run that added files.
+func (b *bloggerImpl) storeCommitMessage(docSourceType DocSourceType, files []string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Store %d %s documents with %s/%s\n\n", len(files), docSourceType, b.runner, b.model))
+	for _, file := range files {
+		sb.WriteString(fmt.Sprintf("- %s\n", file))
+	}
+	return sb.String()
+}
+
+func (b *bloggerImpl) Versions(ctx context.Context, limit int) ([]StoreVersion, error) {
+	vs, err := b.versionedStore()
+	if err != nil {
+		return nil, err
+	}
+	return vs.Versions(ctx, limit)
+}
+
+// CheckoutVersion creates the branch called branch at revision, so the store
+// can be read and written as it was at that commit. If branch is empty, it is
+// named after the commit.
+func (b *bloggerImpl) CheckoutVersion(ctx context.Context, revision string, branch string) (string, error) {
+	vs, err := b.versionedStore()
+	if err != nil {
+		return "", err
+	}
+	hash, err := vs.ResolveRevision(ctx, revision)
+	if err != nil {
+		return "", err
+	}
+	if branch == "" {
+		branch = "checkout-" + hash[:min(len(hash), 8)]
+	}
+	if err = vs.CreateBranch(ctx, branch, hash); err != nil {
+		return "", err
+	}
+	return branch, nil
+}
+
+func (b *bloggerImpl) Branches(ctx context.Context) ([]StoreBranch, error) {
+	vs, err := b.versionedStore()
+	if err != nil {
+		return nil, err
+	}
+	return vs.Branches(ctx)
+}
+
+// DiffBranches compares the chunks stored on the revisions from and to.
+func (b *bloggerImpl) DiffBranches(ctx context.Context, from string, to string) (*BranchDiff, error) {
+	vs, err := b.versionedStore()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &BranchDiff{From: from, To: to}
+	if diff.FromCounts, err = vs.ChunkCounts(ctx, from); err != nil {
+		return nil, err
+	}
+	if diff.ToCounts, err = vs.ChunkCounts(ctx, to); err != nil {
+		return nil, err
+	}
+	if diff.Added, diff.Removed, err = vs.DiffChunks(ctx, from, to); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// MergeBranch merges branch into the store's branch, committing any changes
+// to the store first.
+func (b *bloggerImpl) MergeBranch(ctx context.Context, branch string) (string, error) {
+	vs, err := b.versionedStore()
+	if err != nil {
+		return "", err
+	}
+	if _, err = vs.Commit(ctx, "Commit changes before merging "+branch); err != nil {
+		return "", err
+	}
+	return vs.Merge(ctx, branch)
+}
+
+// SearchDiffChunk is a chunk in the top k results of a search on either of
+// two revisions. A rank of 0 means the chunk was not in that revision's
+// results.
+type SearchDiffChunk struct {
+	Name          string
+	DocSourceType string
+	Content       string
+	FromRank      int
+	ToRank        int
+	FromScore     float32
+	ToScore       float32
+}
+
+// Entered reports whether the chunk is in the To results but not the From
+// results.
+func (c SearchDiffChunk) Entered() bool {
+	return c.FromRank == 0
+}
+
+// Left reports whether the chunk is in the From results but not the To
+// results.
+func (c SearchDiffChunk) Left() bool {
+	return c.ToRank == 0
+}
+
+// SearchDiff compares the top k results of the same search on two revisions.
+type SearchDiff struct {
+	Query  string
+	From   string
+	To     string
+	K      int
+	Chunks []SearchDiffChunk
+}
+
+// DiffSearch runs the same similarity search against the revisions from and
+// to, and returns every chunk in either top k, ordered by its rank in to and
+// then by its rank in from.
+func (b *bloggerImpl) DiffSearch(ctx context.Context, query string, k int, from string, to string) (*SearchDiff, error) {
+	vs, err := b.versionedStore()
+	if err != nil {
+		return nil, err
+	}
+	embedding, err := b.embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	fromDocs, err := vs.SimilaritySearchAsOf(ctx, embedding, k, from)
+	if err != nil {
+		return nil, err
+	}
+	toDocs, err := vs.SimilaritySearchAsOf(ctx, embedding, k, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &SearchDiff{Query: query, From: from, To: to, K: k}
+	chunks := make(map[string]*SearchDiffChunk)
+	keys := make([]string, 0, len(fromDocs)+len(toDocs))
+	chunk := func(doc schema.Document) *SearchDiffChunk {
+		name := fmt.Sprint(doc.Metadata["name"])
+		key := name + "\x00" + doc.PageContent
+		c, ok := chunks[key]
+		if !ok {
+			c = &SearchDiffChunk{
+				Name:          name,
+				DocSourceType: fmt.Sprint(doc.Metadata["doc_source_type"]),
+				Content:       doc.PageContent,
+			}
+			chunks[key] = c
+			keys = append(keys, key)
+		}
+		return c
+	}
+	for i, doc := range fromDocs {
+		c := chunk(doc)
+		c.FromRank, c.FromScore = i+1, doc.Score
+	}
+	for i, doc := range toDocs {
+		c := chunk(doc)
+		c.ToRank, c.ToScore = i+1, doc.Score
+	}
+
+	for _, key := range keys {
+		diff.Chunks = append(diff.Chunks, *chunks[key])
+	}
+	sort.SliceStable(diff.Chunks, func(i, j int) bool {
+		a, b := diff.Chunks[i], diff.Chunks[j]
+		if a.Left() != b.Left() {
+			return !a.Left()
+		}
+		if !a.Left() {
+			return a.ToRank < b.ToRank
+		}
+		return a.FromRank < b.FromRank
+	})
+	return diff, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dolthub/robot-blogger/pkg"
+	"go.uber.org/zap"
+)
+
+func reembed(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("reembed", flag.ContinueOnError)
+	filter := docsFilterFlags(fs)
+	targetRunner := fs.String("target-runner", string(config.Runner), "the llm runner to embed with, ollama or openai")
+	targetModel := fs.String("target-model", "", "the model to embed with")
+	targetStoreName := fs.String("target-store-name", config.StoreName, "the vector store to write to")
+	targetBranch := fs.String("target-branch", "", "the dolt branch to write to")
+	targetVectorDimensions := fs.Int("target-vector-dimensions", 0, "the number of dimensions of the target model's vectors, detected if 0")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *targetModel == "" {
+		return errors.New("reembed requires -target-model")
+	}
+	runner := pkg.Runner(*targetRunner)
+	if runner != pkg.OllamaRunner && runner != pkg.OpenAIRunner {
+		return fmt.Errorf("unsupported runner: %s", *targetRunner)
+	}
+	if runner == pkg.OpenAIRunner && os.Getenv("OPENAI_API_KEY") == "" {
+		return errors.New("OPENAI_API_KEY is required")
+	}
+	if *targetStoreName == config.StoreName && *targetBranch == config.Branch {
+		return errors.New("reembed requires a -target-store-name or -target-branch different from the source")
+	}
+
+	targetConfig := *config
+	targetConfig.WithRunner(runner)
+	targetConfig.WithModel(pkg.Model(*targetModel))
+	targetConfig.WithStoreName(*targetStoreName)
+	targetConfig.WithBranch(*targetBranch)
+	targetConfig.WithVectorDimensions(*targetVectorDimensions)
+	targetConfig.WithAsOf("")
+
+	target, err := pkg.NewBlogger(ctx, &targetConfig, zap.NewNop())
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	p, err := blogger.Reembed(ctx, target, filter(), func(p pkg.ReembedProgress) {
+		fmt.Printf("[%d/%d] %s (%d chunks written, %d documents skipped, %s)\n", p.Done, p.Documents, p.Current, p.Chunks, p.Skipped, p.Elapsed.Round(time.Second))
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("reembedded %d documents, %d chunks, with %s/%s in %s\n", p.Done-p.Skipped, p.Chunks, runner, *targetModel, p.Elapsed.Round(time.Second))
+	return nil
+}
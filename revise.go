@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dolthub/robot-blogger/pkg"
+)
+
+const reviseHelp = `Type feedback on the draft, ie "make the intro shorter", or one of:
+  /show           prints the current draft
+  /undo           discards the latest revision
+  /search <query> adds documents for query to the context
+  /context        prints the retrieved context
+  /save <file>    writes the current draft to file
+  /quit           ends the revision`
+
+// reviseDraft runs the revise command, which revises the draft read from a file.
+func reviseDraft(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("revise requires a draft file")
+	}
+	draft, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	userPrompt := ""
+	if *promptFile != "" {
+		data, err := os.ReadFile(*promptFile)
+		if err != nil {
+			return err
+		}
+		userPrompt = string(data)
+	}
+
+	gen, err := revise(ctx, blogger, &pkg.Generation{
+		Content:         string(draft),
+		UserPrompt:      userPrompt,
+		Topic:           *topic,
+		Format:          *outputFormat,
+		RequestedLength: *length,
+	})
+	if err != nil {
+		return err
+	}
+	if *render != "" {
+		return writeRendered(gen)
+	}
+	return nil
+}
+
+// revise reads feedback from stdin and revises gen until the user quits, and
+// returns the final draft.
+func revise(ctx context.Context, blogger pkg.Blogger, gen *pkg.Generation) (*pkg.Generation, error) {
+	r, err := blogger.Revise(ctx, gen)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println()
+	fmt.Println(reviseHelp)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		fmt.Println()
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		cmd, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		switch cmd {
+		case "/quit", "/exit":
+			return r.Generation(), nil
+		case "/help":
+			fmt.Println(reviseHelp)
+		case "/show":
+			fmt.Println(r.Draft())
+		case "/undo":
+			if !r.Undo() {
+				fmt.Println("nothing to undo")
+				continue
+			}
+			fmt.Println(r.Draft())
+		case "/context":
+			fmt.Println(r.Context())
+		case "/search":
+			if arg == "" {
+				fmt.Println("/search requires a query")
+				continue
+			}
+			if _, err := r.Search(ctx, arg); err != nil {
+				return nil, err
+			}
+		case "/save":
+			if arg == "" {
+				fmt.Println("/save requires a file")
+				continue
+			}
+			if err := os.WriteFile(arg, []byte(r.Draft()+"\n"), 0644); err != nil {
+				return nil, err
+			}
+			fmt.Printf("saved draft to %s\n", arg)
+		default:
+			if strings.HasPrefix(cmd, "/") {
+				fmt.Printf("unknown command: %s\n", cmd)
+				continue
+			}
+			fmt.Println()
+			if _, err := r.Revise(ctx, line); err != nil {
+				return nil, err
+			}
+			fmt.Println()
+			fmt.Printf("LENGTH: %d words\n", r.Generation().Length)
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return r.Generation(), nil
+}
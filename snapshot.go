@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dolthub/robot-blogger/pkg"
+)
+
+func storeSnapshot(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("store snapshot", flag.ContinueOnError)
+	output := fs.String("o", "", "the archive to write, ie corpus.tar.zst")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return errors.New("store snapshot requires -o")
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	manifest, err := blogger.Snapshot(ctx, f)
+	if err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d documents, %d chunks embedded with %s/%s to %s\n", manifest.Documents, manifest.Chunks, manifest.Runner, manifest.Model, *output)
+	return nil
+}
+
+func storeRestore(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: store restore <archive>")
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	manifest, err := blogger.Restore(ctx, f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored %d documents, %d chunks of %s taken %s\n", manifest.Documents, manifest.Chunks, manifest.StoreName, manifest.CreatedAt.Format("2006-01-02 15:04:05"))
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dolthub/robot-blogger/pkg"
+)
+
+func styleCreate(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("style create", flag.ContinueOnError)
+	samples := fs.Int("samples", pkg.DefaultStyleProfileSamples, "the number of stored chunks to sample")
+	docType := fs.String("doc-type", "", "the type of stored documents to sample")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("style create requires a profile name")
+	}
+	if *docType == "" {
+		return errors.New("style create requires -doc-type")
+	}
+
+	profile, err := blogger.CreateStyleProfile(ctx, fs.Arg(0), pkg.DocSourceType(*docType), *samples)
+	if err != nil {
+		return err
+	}
+	fmt.Println(profile.Profile)
+	return nil
+}
+
+func styleList(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	profiles, err := blogger.StyleProfiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDOC TYPE\tMODEL\tCREATED")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, p.DocSourceType, p.Model, p.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
+
+func styleShow(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("style show requires a profile name")
+	}
+	profile, err := blogger.StyleProfile(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(profile.Profile)
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dolthub/robot-blogger/pkg"
+)
+
+func versionsList(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("versions list", flag.ContinueOnError)
+	limit := fs.Int("limit", 20, "the number of commits to list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	versions, err := blogger.Versions(ctx, *limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMMIT\tDATE\tCOMMITTER\tTAGS\tMESSAGE")
+	for _, v := range versions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v.Hash, v.Date.Format("2006-01-02 15:04:05"), v.Committer, strings.Join(v.Tags, ","), firstLine(v.Message))
+	}
+	return w.Flush()
+}
+
+func versionsCheckout(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("versions checkout", flag.ContinueOnError)
+	branch := fs.String("branch", "", "the name of the branch to create, defaults to one named after the commit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("versions checkout requires a commit, tag or branch")
+	}
+
+	name, err := blogger.CheckoutVersion(ctx, fs.Arg(0), *branch)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created branch %s at %s\n", name, fs.Arg(0))
+	fmt.Printf("store to or generate from it with --branch=%s\n", name)
+	return nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+func branchesList(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	branches, err := blogger.Branches(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tCOMMIT\tDATE\tMESSAGE")
+	for _, b := range branches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", b.Name, b.Hash, b.Date.Format("2006-01-02 15:04:05"), firstLine(b.Message))
+	}
+	return w.Flush()
+}
+
+func branchesDiff(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	if len(args) != 2 {
+		return errors.New("branches diff requires two branches")
+	}
+	diff, err := blogger.DiffBranches(ctx, args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	type key struct{ docSourceType, model string }
+	counts := make(map[key][2]int)
+	keys := make([]key, 0)
+	add := func(cs []pkg.ChunkCount, side int) {
+		for _, c := range cs {
+			k := key{c.DocSourceType, c.Model}
+			v, ok := counts[k]
+			if !ok {
+				keys = append(keys, k)
+			}
+			v[side] = c.Count
+			counts[k] = v
+		}
+	}
+	add(diff.FromCounts, 0)
+	add(diff.ToCounts, 1)
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].docSourceType != keys[j].docSourceType {
+			return keys[i].docSourceType < keys[j].docSourceType
+		}
+		return keys[i].model < keys[j].model
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "DOC TYPE\tMODEL\t%s\t%s\tCHANGE\n", strings.ToUpper(diff.From), strings.ToUpper(diff.To))
+	for _, k := range keys {
+		v := counts[k]
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%+d\n", k.docSourceType, k.model, v[0], v[1], v[1]-v[0])
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("%d chunks added, %d chunks removed from %s to %s\n", diff.Added, diff.Removed, diff.From, diff.To)
+	return nil
+}
+
+func branchesMerge(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("branches merge requires a branch")
+	}
+	hash, err := blogger.MergeBranch(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("merged %s at %s\n", args[0], hash)
+	return nil
+}
+
+func searchDiff(ctx context.Context, blogger pkg.Blogger, args []string) error {
+	fs := flag.NewFlagSet("search diff", flag.ContinueOnError)
+	k := fs.Int("k", 10, "the number of results to compare")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 3 {
+		return errors.New("search diff requires two revisions and a query")
+	}
+	query := strings.Join(fs.Args()[2:], " ")
+
+	diff, err := blogger.DiffSearch(ctx, query, *k, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	entered, left := 0, 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\t%s RANK\t%s RANK\t%s SCORE\t%s SCORE\tCHANGE\tNAME\tCHUNK\n", strings.ToUpper(diff.From), strings.ToUpper(diff.To), strings.ToUpper(diff.From), strings.ToUpper(diff.To))
+	for _, c := range diff.Chunks {
+		status, fromRank, toRank, fromScore, toScore, change := " ", "-", "-", "-", "-", "-"
+		if c.Entered() {
+			status = "+"
+			entered++
+		} else if c.Left() {
+			status = "-"
+			left++
+		}
+		if !c.Entered() {
+			fromRank, fromScore = fmt.Sprint(c.FromRank), fmt.Sprintf("%.4f", c.FromScore)
+		}
+		if !c.Left() {
+			toRank, toScore = fmt.Sprint(c.ToRank), fmt.Sprintf("%.4f", c.ToScore)
+		}
+		if !c.Entered() && !c.Left() {
+			change = fmt.Sprintf("%+.4f", c.ToScore-c.FromScore)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", status, fromRank, toRank, fromScore, toScore, change, c.Name, snippet(c.Content, 60))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("%d chunks entered and %d left the top %d from %s to %s\n", entered, left, diff.K, diff.From, diff.To)
+	return nil
+}
+
+// snippet returns the first n characters of s on a single line.
+func snippet(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return s
+}